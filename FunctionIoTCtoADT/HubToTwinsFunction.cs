@@ -47,18 +47,26 @@ namespace Company.Function
                             updateTwinData.AppendAdd("/FanSpeed", deviceMessage["body"]["Force"].Value<double>());
                             updateTwinData.AppendAdd("/RoastingTime", deviceMessage["body"]["RoastingTime"].Value<int>());
                             updateTwinData.AppendAdd("/PowerUsage", deviceMessage["body"]["PowerUsage"].Value<double>());
+                            if (deviceMessage["body"]["co2grams"] != null) updateTwinData.AppendAdd("/co2grams", deviceMessage["body"]["co2grams"].Value<double>());
                             await client.UpdateDigitalTwinAsync(deviceId, updateTwinData);
                         break;
                         case "GrindingSensor":
                             updateTwinData.AppendAdd("/ChasisTemperature", deviceMessage["body"]["ChasisTemperature"].Value<double>());
                             updateTwinData.AppendAdd("/Force", deviceMessage["body"]["Force"].Value<double>());
                             updateTwinData.AppendAdd("/PowerUsage", deviceMessage["body"]["PowerUsage"].Value<double>());
+                            if (deviceMessage["body"]["co2grams"] != null) updateTwinData.AppendAdd("/co2grams", deviceMessage["body"]["co2grams"].Value<double>());
                             updateTwinData.AppendAdd("/Vibration", deviceMessage["body"]["Vibration"].Value<double>());
                             await client.UpdateDigitalTwinAsync(deviceId, updateTwinData);
                         break;
                         case "MouldingSensor":
                             updateTwinData.AppendAdd("/ChasisTemperature", deviceMessage["body"]["ChasisTemperature"].Value<double>());
                             updateTwinData.AppendAdd("/PowerUsage", deviceMessage["body"]["PowerUsage"].Value<double>());
+                            if (deviceMessage["body"]["co2grams"] != null) updateTwinData.AppendAdd("/co2grams", deviceMessage["body"]["co2grams"].Value<double>());
+                            await client.UpdateDigitalTwinAsync(deviceId, updateTwinData);
+                        break;
+                        case "FloorSensor":
+                            updateTwinData.AppendAdd("/Temperature", deviceMessage["body"]["Temperature"].Value<double>());
+                            updateTwinData.AppendAdd("/ComfortIndex", deviceMessage["body"]["ComfortIndex"].Value<double>());
                             await client.UpdateDigitalTwinAsync(deviceId, updateTwinData);
                         break;
                          case "MetroSensor":
@@ -12,9 +12,13 @@ type (
 	}
 
 	config struct {
-		Logger      Config                    `json:"logger"`
-		Application models.CentralApplication `json:"application"`
-		Plant       []simulating.Plant        `json:"plant"`
+		Logger       Config                       `json:"logger"`
+		Application  models.CentralApplication    `json:"application"`
+		Plant        []simulating.Plant           `json:"plant"`
+		MachineTypes []simulating.DTDLMachineType `json:"machineTypes"` // machine types simulated from a DTDL interface
+		Seed         int64                        `json:"seed"`         // seed of the random sources of the devices, random if zero
+		Scenario     string                       `json:"scenario"`     // path of a scenario file of injected faults and events
+		Labels       string                       `json:"labels"`       // path of a CSV or JSON Lines file the anomalies are labelled in
 	}
 )
 
@@ -24,7 +28,13 @@ func newConfig() *config {
 			LogLevel: "Debug",
 			LogsDir:  "./logs",
 		},
-		Application: models.CentralApplication{},
-		Plant:       []simulating.Plant{},
+		Application: models.CentralApplication{
+			BoltMachineModelID:     "dtmi:parnellAerospace:BoltMakerV1;1",
+			FanningMachineModelID:  "dtmi:thesisrp:FanningMachineV1;1",
+			GrindingMachineModelID: "dtmi:thesisrp:GrindingMachineV1;1",
+			MouldingMachineModelID: "dtmi:thesisrp:MouldingMachineV1;1",
+		},
+		Plant:        []simulating.Plant{},
+		MachineTypes: []simulating.DTDLMachineType{},
 	}
 }
@@ -2,13 +2,15 @@ module github.com/iot-for-all/iiot-oee
 
 go 1.17
 
-require github.com/amenzhinsky/iothub v0.9.0
+require (
+	github.com/amenzhinsky/iothub v0.9.0
+	github.com/mitchellh/mapstructure v1.4.3
+)
 
 require (
 	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/magiconair/properties v1.8.5 // indirect
-	github.com/mitchellh/mapstructure v1.4.3 // indirect
 	github.com/pelletier/go-toml v1.9.4 // indirect
 	github.com/spf13/afero v1.6.0 // indirect
 	github.com/spf13/cast v1.4.1 // indirect
@@ -8,9 +8,11 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
+	_ "time/tzdata" // plant time zones also resolve on hosts without a time zone database, like Windows
 
-	"github.com/iot-for-all/iiot-oee/pkg/models"
 	"github.com/iot-for-all/iiot-oee/pkg/simulating"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -37,29 +39,195 @@ func main() {
 	}
 	initLogger(cfg)
 
+	// register the machine types that are simulated from DTDL interfaces
+	for i := range cfg.MachineTypes {
+		machineType := &cfg.MachineTypes[i]
+		if !filepath.IsAbs(machineType.Model) {
+			machineType.Model = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), machineType.Model)
+		}
+		if err := simulating.RegisterDTDLMachine(machineType); err != nil {
+			panic(fmt.Errorf("failed to register machine type %s. %w", machineType.Name, err))
+		}
+		log.Debug().Str("kind", machineType.Name).Str("model", machineType.Model).Msg("Registered DTDL machine type")
+	}
+
+	// the same seed makes every device send the same telemetry values on every run
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	log.Info().Int64("seed", cfg.Seed).Msg("Seeding random sources")
+
+	// play the scenario of injected faults and events from now on
+	var scenario *simulating.Scenario
+	if cfg.Scenario != "" {
+		if !filepath.IsAbs(cfg.Scenario) {
+			cfg.Scenario = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), cfg.Scenario)
+		}
+		scenario, err = simulating.LoadScenario(cfg.Scenario, time.Now().UTC())
+		if err != nil {
+			panic(fmt.Errorf("failed to load scenario %s. %w", cfg.Scenario, err))
+		}
+		log.Info().Str("scenario", cfg.Scenario).Int("events", len(scenario.Events)).Msg("Playing scenario")
+		go scenario.Play(ctx)
+	}
+
+	// label the anomalies the devices report as ground truth for anomaly detection
+	var labels *simulating.AnomalyLabels
+	if cfg.Labels != "" {
+		labels, err = simulating.NewAnomalyLabels(cfg.Labels)
+		if err != nil {
+			panic(fmt.Errorf("failed to create labels file %s. %w", cfg.Labels, err))
+		}
+		log.Info().Str("labels", cfg.Labels).Msg("Labelling anomalies")
+	}
+
 	// start devices
 	for _, plant := range cfg.Plant {
+		seed := cfg.Seed
+		if plant.Seed != 0 {
+			seed = plant.Seed
+		}
 		log.Debug().Str("plant", plant.Name).Msg("Starting up plant")
-		for i := 1; i <= plant.BoltMachine.Count; i++ {
-			log.Debug().Int("BoltMachine", i).Msg("Starting up bolt machine")
-			deviceID := fmt.Sprintf("%s-BoltMachine-%d", plant.Name, i)
-			boltMachine := models.BoltMachine{
-				PlantName:          plant.Name,
-				ProductionLine:     fmt.Sprintf("ProductionLine %d", i),
-				ShiftNumber:        0,
-				BatchNumber:        0,
-				TotalPartsMade:     0,
-				DefectivePartsMade: 0,
-				MachineHealth:      "Healthy",
-				OilLevel:           100,
-				Temperature:        100,
-				Kwh:                92,
-				PlannedKwH:         90,
-				Format:             plant.BoltMachine.Format,
+		if plant.Carbon != nil && plant.Carbon.File != "" && !filepath.IsAbs(plant.Carbon.File) {
+			plant.Carbon.File = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), plant.Carbon.File)
+		}
+		carbon, err := simulating.NewCarbonProfile(plant.Carbon)
+		if err != nil {
+			panic(fmt.Errorf("failed to read carbon intensity of plant %s. %w", plant.Name, err))
+		}
+		if plant.Tariff != nil && plant.Tariff.File != "" && !filepath.IsAbs(plant.Tariff.File) {
+			plant.Tariff.File = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), plant.Tariff.File)
+		}
+		tariff, err := simulating.NewTariff(plant.Tariff)
+		if err != nil {
+			panic(fmt.Errorf("failed to read tariff of plant %s. %w", plant.Name, err))
+		}
+		meter, err := simulating.NewPlantMeter(plant.Meter)
+		if err != nil {
+			panic(fmt.Errorf("failed to read meter of plant %s. %w", plant.Name, err))
+		}
+		location, err := plant.Location()
+		if err != nil {
+			panic(fmt.Errorf("failed to read time zone of plant %s. %w", plant.Name, err))
+		}
+		calendar, err := simulating.NewShiftCalendar(plant.Calendar)
+		if err != nil {
+			panic(fmt.Errorf("failed to read shift calendar of plant %s. %w", plant.Name, err))
+		}
+		if plant.Outdoor != nil && plant.Outdoor.File != "" && !filepath.IsAbs(plant.Outdoor.File) {
+			plant.Outdoor.File = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), plant.Outdoor.File)
+		}
+		weather, err := simulating.NewWeather(plant.Outdoor)
+		if err != nil {
+			panic(fmt.Errorf("failed to read weather of plant %s. %w", plant.Name, err))
+		}
+		floors, err := plant.FactoryFloors(weather)
+		if err != nil {
+			panic(fmt.Errorf("failed to read floors of plant %s. %w", plant.Name, err))
+		}
+		for _, kind := range plant.UnknownMachineKinds() {
+			log.Warn().Str("plant", plant.Name).Str("kind", kind).Msg("Ignoring unknown machine kind")
+		}
+		lines, err := plant.ProductionLines()
+		if err != nil {
+			panic(fmt.Errorf("failed to read production lines of plant %s. %w", plant.Name, err))
+		}
+		plan, err := plant.ProductionPlan()
+		if err != nil {
+			panic(fmt.Errorf("failed to read production plan of plant %s. %w", plant.Name, err))
+		}
+		for _, kind := range simulating.MachineKinds() {
+			machineCfg, err := plant.MachineConfig(kind)
+			if err != nil {
+				panic(fmt.Errorf("failed to read %s configuration of plant %s. %w", kind, plant.Name, err))
+			}
+			if machineCfg == nil {
+				continue
+			}
+			for i := 1; i <= machineCfg.Count; i++ {
+				log.Debug().Int(kind, i).Msg("Starting up machine")
+				spec := &simulating.MachineSpec{
+					Kind:           kind,
+					DeviceID:       simulating.MachineDeviceID(plant.Name, kind, i),
+					PlantName:      plant.Name,
+					ProductionLine: fmt.Sprintf("ProductionLine %d", i),
+					Index:          i,
+					Config:         machineCfg,
+					App:            &cfg.Application,
+				}
+				spec.Rand = simulating.NewDeviceRand(seed, spec.DeviceID)
+				spec.Scenario = scenario
+				spec.Labels = labels
+				spec.Carbon = carbon
+				spec.Tariff = tariff
+				spec.Meter = meter
+				spec.Calendar = calendar
+				spec.Location = location
+				spec.Products = plan.Products(kind, i)
+				spec.Weather = weather
+				// machines give off their heat to the floor they are on
+				for _, floor := range floors {
+					if floor.Contains(kind, i) {
+						spec.Floor = floor
+					}
+				}
+				// machines in a production line report the line they belong to
+				for _, line := range lines {
+					if step := line.Step(kind, i); step != nil {
+						spec.ProductionLine = line.Name()
+						spec.Line = step
+					}
+				}
+				machine, err := simulating.NewMachine(spec)
+				if err != nil {
+					panic(fmt.Errorf("failed to create machine %s. %w", spec.DeviceID, err))
+				}
+				device := simulating.NewDevice(ctx, spec, machine)
+
+				// start the device simulation of machines
+				go device.Start()
+			}
+		}
+
+		// the plant meter measures the machines of the plant plus its base loads
+		if meter != nil {
+			log.Debug().Str("plant", plant.Name).Msg("Starting up plant meter")
+			spec := &simulating.MachineSpec{
+				Kind:      simulating.PlantMeterKind,
+				DeviceID:  simulating.MachineDeviceID(plant.Name, simulating.PlantMeterKind, 1),
+				PlantName: plant.Name,
+				Index:     1,
+				Config:    meter.MachineConfig(),
+				App:       &cfg.Application,
 			}
-			device := simulating.NewDevice(ctx, &cfg.Application, deviceID, &boltMachine)
+			spec.Rand = simulating.NewDeviceRand(seed, spec.DeviceID)
+			spec.Scenario = scenario
+			spec.Labels = labels
+			spec.Carbon = carbon
+			spec.Tariff = tariff
+			spec.Calendar = calendar
+			spec.Location = location
+			device := simulating.NewDevice(ctx, spec, simulating.NewPlantMeterMachine(spec, meter))
+			go device.Start()
+		}
 
-			// start the device simulation of machines
+		// every floor has a sensor that measures its climate
+		for i, floor := range floors {
+			log.Debug().Str("plant", plant.Name).Str("floor", floor.Name()).Msg("Starting up floor sensor")
+			spec := &simulating.MachineSpec{
+				Kind:      simulating.FloorSensorKind,
+				DeviceID:  simulating.MachineDeviceID(plant.Name, simulating.FloorSensorKind, i+1),
+				PlantName: plant.Name,
+				Index:     i + 1,
+				Config:    floor.MachineConfig(),
+				App:       &cfg.Application,
+			}
+			spec.Rand = simulating.NewDeviceRand(seed, spec.DeviceID)
+			spec.Scenario = scenario
+			spec.Labels = labels
+			spec.Calendar = calendar
+			spec.Location = location
+			device := simulating.NewDevice(ctx, spec, simulating.NewFloorSensorMachine(spec, floor))
 			go device.Start()
 		}
 	}
@@ -68,6 +236,11 @@ func main() {
 	<-sig
 
 	cancel() // Wait for device to completely shut down.
+
+	// anomalies still going on end with the simulation
+	if err := labels.Close(time.Now().UTC()); err != nil {
+		log.Error().Err(err).Str("labels", cfg.Labels).Msg("failed to close labels file")
+	}
 }
 
 // loadConfig loads the configuration file
@@ -111,7 +284,12 @@ func loadConfig() (*config, error) {
     "provisioningUrl": "global.azure-devices-provisioning.net",
     "idScope": "CHANGE THIS -- YOUR_APP_IDSCOPE",
     "masterKey": "CHANGE THIS -- DPS Master key",
-    "boltMachineModelID": "dtmi:parnellAerospace:BoltMakerV1;1"
+    "boltMachineModelID": "dtmi:parnellAerospace:BoltMakerV1;1",
+    "fanningMachineModelID": "dtmi:thesisrp:FanningMachineV1;1",
+    "grindingMachineModelID": "dtmi:thesisrp:GrindingMachineV1;1",
+    "mouldingMachineModelID": "dtmi:thesisrp:MouldingMachineV1;1",
+    "plantMeterModelID": "dtmi:thesisrp:PlantMeterV1;1",
+    "floorSensorModelID": "dtmi:thesisrp:FloorSensorV1;1"
   },
   "plant": [
     {
@@ -119,6 +297,18 @@ func loadConfig() (*config, error) {
       "boltMachine":{
         "count": 2,
         "format": "json"
+      },
+      "fanningMachine":{
+        "count": 1,
+        "format": "json"
+      },
+      "grindingMachine":{
+        "count": 1,
+        "format": "json"
+      },
+      "mouldingMachine":{
+        "count": 1,
+        "format": "json"
       }
     },
     {
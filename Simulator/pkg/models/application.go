@@ -2,9 +2,14 @@ package models
 
 type (
 	CentralApplication struct {
-		ProvisioningURL    string `json:"provisioningUrl"`    // DPS provisioning URL.
-		IDScope            string `json:"idScope"`            // the id scope of the provisioning endpoint.
-		MasterKey          string `json:"masterKey"`          // the master SAS key of the provisioning endpoint.
-		BoltMachineModelID string `json:"BoltMachineModelID"` // the bolt machine device model ID.
+		ProvisioningURL        string `json:"provisioningUrl"`        // DPS provisioning URL.
+		IDScope                string `json:"idScope"`                // the id scope of the provisioning endpoint.
+		MasterKey              string `json:"masterKey"`              // the master SAS key of the provisioning endpoint.
+		BoltMachineModelID     string `json:"BoltMachineModelID"`     // the bolt machine device model ID.
+		FanningMachineModelID  string `json:"FanningMachineModelID"`  // the fanning machine device model ID.
+		GrindingMachineModelID string `json:"GrindingMachineModelID"` // the grinding machine device model ID.
+		MouldingMachineModelID string `json:"MouldingMachineModelID"` // the moulding machine device model ID.
+		PlantMeterModelID      string `json:"PlantMeterModelID"`      // the plant meter device model ID.
+		FloorSensorModelID     string `json:"FloorSensorModelID"`     // the floor sensor device model ID.
 	}
 )
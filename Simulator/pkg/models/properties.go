@@ -3,6 +3,9 @@ package models
 import "time"
 
 type (
+	// Telemetry holds the named telemetry values of a single message.
+	Telemetry map[string]interface{}
+
 	Properties struct {
 		IsMachineOn        bool `json:"isMachineOn"`
 		TelemetryFrequency int  `json:"telemetryFrequency"`
@@ -29,6 +32,9 @@ type (
 		Temperature        float64   `json:"temperature"`
 		Kwh		           float64   `json:"kwh"`
 		PlannedKwH		   float64	 `json:"plannedkwh"`
+		MaintenanceEvent   string    `json:"maintenanceEvent"`
+		Vibration          float64   `json:"vibration"`
+		ToolWear           float64   `json:"toolWear"`
 	}
 
 	BoltMachine struct {
@@ -44,5 +50,112 @@ type (
 		Kwh 	           float64 `json:"kwh"`
 		PlannedKwH		   float64 `json:"plannedkwh"`
 		Format             string  `json:"format"`
+
+		RefillRequested   bool      `json:"refillRequested"`   // the refillOil command was received
+		NextScheduledFill time.Time `json:"nextScheduledFill"` // time of the next scheduled oil refill
+		PendingRefill     string    `json:"pendingRefill"`     // maintenance event that will refill the oil, if any
+		RefillDue         time.Time `json:"refillDue"`         // time the pending refill completes
+
+		ToolParts           int       `json:"toolParts"`           // parts made with the current tool
+		ToolLife            int       `json:"toolLife"`            // parts the current tool makes before it breaks
+		ToolChangeRequested bool      `json:"toolChangeRequested"` // the changeTool command was received
+		PendingToolChange   string    `json:"pendingToolChange"`   // maintenance event that will replace the tool, if any
+		ToolChangeDue       time.Time `json:"toolChangeDue"`       // time the pending tool change completes
+	}
+
+	FanningMachineTelemetryMessage struct {
+		DeviceType        string    `json:"DeviceType"`
+		PlantName         string    `json:"plantName"`
+		ProductionLine    string    `json:"productionLine"`
+		MessageTimestamp  time.Time `json:"messageTimestamp"`
+		ChasisTemperature float64   `json:"ChasisTemperature"`
+		Force             float64   `json:"Force"` // fan speed in rpm, mapped to FanSpeed by the ADT function
+		RoastingTime      int       `json:"RoastingTime"`
+		PowerUsage        float64   `json:"PowerUsage"`
+	}
+
+	FanningMachine struct {
+		PlantName            string    `json:"plantName"`
+		ProductionLine       string    `json:"productionLine"`
+		Phase                string    `json:"phase"`
+		PhaseStarted         time.Time `json:"phaseStarted"`
+		RoastDurationMinutes int       `json:"roastDurationMinutes"`
+		CoolDurationMinutes  int       `json:"coolDurationMinutes"`
+		ChasisTemperature    float64   `json:"chasisTemperature"`
+		FanSpeed             float64   `json:"fanSpeed"`
+		PowerUsage           float64   `json:"powerUsage"`
+		Format               string    `json:"format"`
+	}
+
+	GrindingMachineTelemetryMessage struct {
+		DeviceType        string    `json:"DeviceType"`
+		PlantName         string    `json:"plantName"`
+		ProductionLine    string    `json:"productionLine"`
+		MessageTimestamp  time.Time `json:"messageTimestamp"`
+		ChasisTemperature float64   `json:"ChasisTemperature"`
+		Force             float64   `json:"Force"`
+		Vibration         float64   `json:"Vibration"`
+		GrindingTime      int       `json:"GrindingTime"`
+		PowerUsage        float64   `json:"PowerUsage"`
+	}
+
+	PlantMeterTelemetryMessage struct {
+		PlantName        string             `json:"plantName"`
+		MessageTimestamp time.Time          `json:"messageTimestamp"`
+		Kwh              float64            `json:"kwh"`          // energy used by the plant since the previous message
+		MachineKwh       float64            `json:"machineKwh"`   // part of the energy used by the simulated machines
+		BaseLoadKwh      float64            `json:"baseLoadKwh"`  // part of the energy used by the base loads
+		BaseLoads        map[string]float64 `json:"baseLoads"`    // energy used by every base load
+		Power            float64            `json:"power"`        // average power since the previous message in kilowatt
+		MeterReading     float64            `json:"meterReading"` // energy used since the simulator started
+	}
+
+	FloorSensorTelemetryMessage struct {
+		DeviceType         string    `json:"DeviceType"`
+		PlantName          string    `json:"plantName"`
+		MessageTimestamp   time.Time `json:"messageTimestamp"`
+		FloorId            string    `json:"FloorId"`
+		FloorName          string    `json:"FloorName"`
+		Temperature        float64   `json:"Temperature"`
+		ComfortIndex       float64   `json:"ComfortIndex"`
+		OutdoorTemperature float64   `json:"outdoorTemperature"`
+		OutdoorHumidity    float64   `json:"outdoorHumidity"` // outdoor relative humidity in percent
+		MachineHeat        float64   `json:"machineHeat"`     // heat the machines give off to the floor in kilowatt
+		HeatingPower       float64   `json:"heatingPower"`    // power of the floor heating in kilowatt
+	}
+
+	GrindingMachine struct {
+		PlantName            string    `json:"plantName"`
+		ProductionLine       string    `json:"productionLine"`
+		BatchStarted         time.Time `json:"batchStarted"`
+		LastUpdate           time.Time `json:"lastUpdate"`
+		GrindDurationMinutes int       `json:"grindDurationMinutes"`
+		WheelLifeHours       float64   `json:"wheelLifeHours"`
+		WheelWear            float64   `json:"wheelWear"`
+		ChasisTemperature    float64   `json:"chasisTemperature"`
+		Format               string    `json:"format"`
+	}
+
+	MouldingMachineTelemetryMessage struct {
+		DeviceType        string    `json:"DeviceType"`
+		PlantName         string    `json:"plantName"`
+		ProductionLine    string    `json:"productionLine"`
+		MessageTimestamp  time.Time `json:"messageTimestamp"`
+		CyclePhase        string    `json:"CyclePhase"`
+		ChasisTemperature float64   `json:"ChasisTemperature"`
+		PowerUsage        float64   `json:"PowerUsage"`
+	}
+
+	MouldingMachine struct {
+		PlantName           string    `json:"plantName"`
+		ProductionLine      string    `json:"productionLine"`
+		Phase               string    `json:"phase"`
+		PhaseStarted        time.Time `json:"phaseStarted"`
+		LastUpdate          time.Time `json:"lastUpdate"`
+		SetpointTemperature float64   `json:"setpointTemperature"`
+		EjectTemperature    float64   `json:"ejectTemperature"`
+		InjectionSeconds    int       `json:"injectionSeconds"`
+		ChasisTemperature   float64   `json:"chasisTemperature"`
+		Format              string    `json:"format"`
 	}
 )
@@ -0,0 +1,319 @@
+package simulating
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+	"github.com/rs/zerolog/log"
+)
+
+const boltOperatingTemperature = 70.0 // nominal temperature of the heated bolt dies
+
+// defaultBoltProduct is the product bolt machines make when the production plan has none for them, 100 parts per
+// minute at the ideal cycle time.
+var defaultBoltProduct = ProductConfig{CycleTime: 600 * time.Millisecond, DefectRate: 0.01}
+
+type boltMachine struct {
+	modelID     string              // device model ID of bolt machines.
+	energy      *EnergyConfig       // energy model of the bolt machine
+	maintenance *MaintenanceConfig  // oil consumption and maintenance of the bolt machine
+	wear        *WearConfig         // tool wear of the bolt machine
+	rand        *rand.Rand          // random source of the device
+	machine     *models.BoltMachine // bolt machine state
+}
+
+func init() {
+	RegisterMachine("boltMachine", newBoltMachine)
+	energyDefaults["boltMachine"] = EnergyConfig{
+		BasePower:       12,
+		StandbyPower:    2,
+		EnergyPerPart:   0.004,
+		HeaterPower:     0.15,
+		WarningOverhead: 0.15,
+	}
+}
+
+func newBoltMachine(spec *MachineSpec) (Machine, error) {
+	energy := spec.Config.Energy
+	if energy == nil {
+		energy = defaultEnergyConfig("boltMachine")
+	}
+	maintenance := spec.Config.Maintenance
+	if maintenance == nil {
+		maintenance = defaultMaintenanceConfig()
+	}
+	wear := spec.Config.Wear
+	if wear == nil {
+		wear = defaultWearConfig()
+	}
+	return &boltMachine{
+		modelID:     spec.App.BoltMachineModelID,
+		energy:      energy,
+		maintenance: maintenance,
+		wear:        wear,
+		rand:        spec.Rand,
+		machine: &models.BoltMachine{
+			PlantName:          spec.PlantName,
+			ProductionLine:     spec.ProductionLine,
+			ShiftNumber:        0,
+			BatchNumber:        0,
+			TotalPartsMade:     0,
+			DefectivePartsMade: 0,
+			MachineHealth:      "Healthy",
+			OilLevel:           100,
+			Temperature:        100,
+			Kwh:                0,
+			PlannedKwH:         0,
+			Format:             spec.Config.Format,
+		},
+	}, nil
+}
+
+func (m *boltMachine) ModelID() string {
+	return m.modelID
+}
+
+func (m *boltMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	maintenanceEvent := m.maintain(tick)
+	if event := m.maintainTool(tick); event != "" {
+		maintenanceEvent = event
+	}
+	product := tick.Product
+	if product == nil {
+		product = &defaultBoltProduct
+	}
+	wear := getWear(m.machine.ToolParts, m.machine.ToolLife)
+
+	// the machine makes 90 to 99% of the ideal output of its product at the load of the step while it is running, or
+	// what its production line allows, and a share of the parts is defective, which grows as the tool wears
+	idealPartsPerMinute := time.Minute.Seconds() / product.CycleTime.Seconds()
+	totalPartsMade := int(idealPartsPerMinute * float64(90+m.rand.Intn(10)) / 100 * tick.Load * tick.RunTime.Minutes())
+	if tick.Line != nil {
+		totalPartsMade = tick.LineOutput
+	}
+	defectivePartsMade := getDefects(totalPartsMade, math.Min(1, product.DefectRate+m.wear.DefectIncrease*wear*wear), m.rand)
+
+	// oil is used for every part made, until the machine stops itself to protect the dies
+	oilPerPart := m.maintenance.OilPerPart
+	if product.OilPerPart > 0 {
+		oilPerPart = product.OilPerPart
+	}
+	m.machine.OilLevel = math.Max(0, math.Round((m.machine.OilLevel-oilPerPart*float64(totalPartsMade))*100)/100)
+
+	if m.machine.OilLevel < 10.0 {
+		m.machine.MachineHealth = "Error"
+		totalPartsMade = 0
+		defectivePartsMade = 0
+		// the step counts as downtime in the OEE, the energy and the utilities as well
+		tick.RunTime = 0
+
+		// the machine stays down until its oil is refilled
+		tick.StateMachine.Hold(StateUnplannedDowntime, ReasonLowOil, tick.Now)
+		if m.maintenance.Technician && m.machine.PendingRefill == "" {
+			m.machine.PendingRefill = MaintenanceTechnicianRefill
+			m.machine.RefillDue = tick.Now.Add(m.maintenance.ResponseTime.sample(m.rand))
+		}
+	} else if m.machine.OilLevel < 25.0 {
+		m.machine.MachineHealth = "Warning"
+		totalPartsMade /= 2
+		if defectivePartsMade > totalPartsMade {
+			defectivePartsMade = totalPartsMade
+		}
+	} else {
+		m.machine.MachineHealth = "Healthy"
+	}
+	// the line only moves the parts the machine made
+	if tick.Line != nil {
+		tick.LineOutput = totalPartsMade
+	}
+
+	// a tool that is not changed in time breaks, and the machine stays down until it is replaced
+	m.machine.ToolParts += totalPartsMade
+	if m.machine.ToolParts >= m.machine.ToolLife && m.machine.PendingToolChange == "" {
+		m.machine.PendingToolChange = MaintenanceToolRepair
+		m.machine.ToolChangeDue = tick.Now.Add(m.wear.RepairDuration)
+		tick.StateMachine.Hold(StateUnplannedDowntime, ReasonToolBreakage, tick.Now)
+	}
+
+	// the dies run warmer in a warm hall
+	ambient := m.energy.AmbientTemperature
+	if ambient == 0 {
+		ambient = tick.Ambient
+	}
+	offset := (ambient - referenceTemperature) * 0.5
+	if tick.RunTime == 0 {
+		// the heaters are off, so the dies cool down
+		m.machine.Temperature = math.Max(ambient, m.machine.Temperature-0.5)
+	} else if m.machine.Temperature >= 90+offset {
+		m.machine.Temperature -= 0.5
+	} else if m.machine.Temperature <= 50+offset {
+		m.machine.Temperature += 0.5
+	} else {
+		if m.rand.Intn(100) > 50 {
+			m.machine.Temperature += 0.5
+		} else {
+			m.machine.Temperature -= 0.5
+		}
+	}
+
+	// energy follows the output, the heater load, the health of the machine and the wear of its tool;
+	// the planned energy is what a healthy machine with a new tool would use running the whole interval at the ideal
+	// cycle time of its product, unless it is switched off
+	planned := *m.energy
+	planned.AmbientTemperature = ambient
+	if product.EnergyPerPart > 0 {
+		planned.EnergyPerPart = product.EnergyPerPart
+	}
+	energy := planned
+	energy.EnergyPerPart *= 1 + m.wear.EnergyIncrease*wear
+	kwh := energy.getEnergyUsage(tick.RunTime, tick.Interval-tick.RunTime, totalPartsMade, m.machine.Temperature, m.machine.MachineHealth)
+	plannedKwh := 0.0
+	if tick.State != StateOff {
+		plannedKwh = planned.getEnergyUsage(tick.Interval, 0, int(idealPartsPerMinute*tick.Interval.Minutes()), boltOperatingTemperature, "Healthy")
+	}
+	vibration := m.rand.Float64() * 0.2
+	if tick.RunTime > 0 {
+		vibration = m.wear.getVibration(wear, m.rand)
+	}
+
+	m.machine.ShiftNumber = tick.ShiftNumber
+	m.machine.BatchNumber = tick.BatchNumber
+	m.machine.TotalPartsMade = totalPartsMade
+	m.machine.DefectivePartsMade = defectivePartsMade
+	m.machine.Kwh = math.Round(kwh*1000) / 1000
+	m.machine.PlannedKwH = math.Round(plannedKwh*1000) / 1000
+
+	telemetry, err := toTelemetry(models.BoltMachineTelemetryMessage{
+		PlantName:          m.machine.PlantName,
+		ProductionLine:     m.machine.ProductionLine,
+		ShiftNumber:        tick.ShiftNumber,
+		BatchNumber:        tick.BatchNumber,
+		MessageTimestamp:   tick.Now,
+		TotalPartsMade:     totalPartsMade,
+		DefectivePartsMade: defectivePartsMade,
+		MachineHealth:      m.machine.MachineHealth,
+		OilLevel:           m.machine.OilLevel,
+		Temperature:        m.machine.Temperature,
+		Kwh:                m.machine.Kwh,
+		PlannedKwH:         m.machine.PlannedKwH,
+		MaintenanceEvent:   maintenanceEvent,
+		Vibration:          math.Round(vibration*100) / 100,
+		ToolWear:           math.Round(float64(m.machine.ToolParts)/float64(m.wear.ToolLife)*10000) / 100,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the parts the tool makes until it breaks are hidden from the machine, and only reported as ground truth
+	if m.wear.ExportRul {
+		rulParts := m.machine.ToolLife - m.machine.ToolParts
+		if rulParts < 0 {
+			rulParts = 0
+		}
+		telemetry["rulParts"] = rulParts
+		telemetry["rulMinutes"] = math.Round(float64(rulParts)/idealPartsPerMinute*10) / 10
+	}
+	return telemetry, nil
+}
+
+// IdealCycleTime gets the ideal cycle time of the product the machine makes in the step.
+func (m *boltMachine) IdealCycleTime(tick *Tick) time.Duration {
+	if tick.Product != nil {
+		return tick.Product.CycleTime
+	}
+	return defaultBoltProduct.CycleTime
+}
+
+// maintain refills the oil when a maintenance event completes and starts scheduled refills,
+// returning the maintenance event that completed in this step, if any.
+func (m *boltMachine) maintain(tick *Tick) string {
+	event := ""
+	if m.machine.RefillRequested {
+		event = MaintenanceCommandRefill
+	} else if m.machine.PendingRefill != "" && !tick.Now.Before(m.machine.RefillDue) {
+		event = m.machine.PendingRefill
+	}
+	if event != "" {
+		m.machine.RefillRequested = false
+		m.machine.PendingRefill = ""
+		m.machine.OilLevel = 100.0
+		m.machine.MachineHealth = "Healthy"
+		// a machine that is also waiting for a new tool stays down
+		if m.machine.PendingToolChange == "" {
+			tick.StateMachine.Release(tick.Now)
+		}
+		log.Info().Str("plant", m.machine.PlantName).Str("line", m.machine.ProductionLine).Str("event", event).Msg("refilled oil")
+	}
+
+	// scheduled refills stop the machine for a while, unless a refill is already pending
+	if m.maintenance.ScheduleEvery > 0 && tick.State != StateOff {
+		if m.machine.NextScheduledFill.IsZero() {
+			m.machine.NextScheduledFill = tick.Now.Add(m.maintenance.ScheduleEvery)
+		}
+		if !tick.Now.Before(m.machine.NextScheduledFill) {
+			m.machine.NextScheduledFill = tick.Now.Add(m.maintenance.ScheduleEvery)
+			if m.machine.PendingRefill == "" {
+				m.machine.PendingRefill = MaintenanceScheduledRefill
+				m.machine.RefillDue = tick.Now.Add(m.maintenance.RefillDuration)
+				tick.StateMachine.Hold(StatePlannedDowntime, ReasonScheduledRefill, tick.Now)
+			}
+		}
+	}
+	return event
+}
+
+// maintainTool replaces the tool when a tool change or repair completes and starts tool changes, returning the
+// maintenance event that completed in this step, if any.
+func (m *boltMachine) maintainTool(tick *Tick) string {
+	if m.machine.ToolLife == 0 {
+		m.machine.ToolLife = m.wear.drawToolLife(m.rand)
+	}
+	event := ""
+	if m.machine.PendingToolChange != "" && !tick.Now.Before(m.machine.ToolChangeDue) {
+		event = m.machine.PendingToolChange
+		m.machine.PendingToolChange = ""
+		m.machine.ToolParts = 0
+		m.machine.ToolLife = m.wear.drawToolLife(m.rand)
+		// a machine that is also waiting for oil stays down
+		if m.machine.PendingRefill == "" && m.machine.OilLevel >= 10.0 {
+			tick.StateMachine.Release(tick.Now)
+		}
+		log.Info().Str("plant", m.machine.PlantName).Str("line", m.machine.ProductionLine).Str("event", event).Msg("replaced tool")
+	}
+
+	// the tool is changed on request, or once it made its planned share of the tool life
+	scheduled := m.wear.ChangeAt > 0 && float64(m.machine.ToolParts) >= m.wear.ChangeAt*float64(m.wear.ToolLife)
+	if m.machine.PendingToolChange == "" && tick.State != StateOff && (m.machine.ToolChangeRequested || scheduled) {
+		m.machine.PendingToolChange = MaintenanceScheduledToolChange
+		if m.machine.ToolChangeRequested {
+			m.machine.PendingToolChange = MaintenanceCommandToolChange
+		}
+		m.machine.ToolChangeDue = tick.Now.Add(m.wear.ChangeDuration)
+		tick.StateMachine.Hold(StatePlannedDowntime, ReasonToolChange, tick.Now)
+	}
+	m.machine.ToolChangeRequested = false
+	return event
+}
+
+func (m *boltMachine) ApplyProperty(name string, value interface{}) bool {
+	return false
+}
+
+func (m *boltMachine) Commands() []string {
+	return []string{"refillOil", "changeTool"}
+}
+
+func (m *boltMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	switch name {
+	case "refillOil":
+		// the oil is refilled with the next telemetry message
+		m.machine.RefillRequested = true
+		return map[string]interface{}{"oilLevel": m.machine.OilLevel}, nil
+	case "changeTool":
+		// the tool change starts with the next telemetry message
+		m.machine.ToolChangeRequested = true
+		return map[string]interface{}{"toolParts": m.machine.ToolParts}, nil
+	}
+	return nil, errUnknownCommand
+}
@@ -0,0 +1,40 @@
+package simulating
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+func TestBoltLowOilStopsRunTime(t *testing.T) {
+	rand := NewDeviceRand(1, "Test-BoltMachine-1")
+	machine, err := newBoltMachine(&MachineSpec{Config: &MachineConfig{}, Rand: rand, App: &models.CentralApplication{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bolt := machine.(*boltMachine)
+	bolt.machine.OilLevel = 5
+
+	now := time.Date(2022, 5, 24, 9, 0, 0, 0, time.UTC)
+	tick := &Tick{
+		Now:          now,
+		Local:        now,
+		Interval:     10 * time.Minute,
+		Load:         1,
+		State:        StateRunning,
+		RunTime:      10 * time.Minute,
+		StateMachine: NewStateMachine(nil, nil, time.UTC, now.Add(-10*time.Minute), rand),
+	}
+	if _, err := bolt.NextTelemetry(tick); err != nil {
+		t.Fatal(err)
+	}
+
+	// the OEE and the utilities of the step read the run time of the tick
+	if tick.RunTime != 0 {
+		t.Errorf("RunTime = %v, want 0", tick.RunTime)
+	}
+	if state := tick.StateMachine.Current(); state != StateUnplannedDowntime {
+		t.Errorf("state = %s, want %s", state, StateUnplannedDowntime)
+	}
+}
@@ -0,0 +1,246 @@
+package simulating
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	ReasonBreak         = "BREAK"           // the shift is on a break
+	ReasonNoShift       = "NO_SHIFT"        // no shift is scheduled on a working day
+	ReasonNonWorkingDay = "NON_WORKING_DAY" // the plant does not work on this day of the week
+	ReasonHoliday       = "HOLIDAY"         // the plant is closed for a holiday
+
+	calendarHorizon = 14 // days the calendar looks ahead for the end of a stop
+)
+
+type (
+	// CalendarConfig configures the shifts a plant works, outside of which its machines do not produce.
+	CalendarConfig struct {
+		Shifts         []ShiftConfig `json:"shifts"`         // shifts worked on every working day
+		NonWorkingDays []string      `json:"nonWorkingDays"` // weekdays the plant does not work, e.g. Sat and Sun
+		Holidays       []string      `json:"holidays"`       // dates the plant does not work, YYYY-MM-DD
+	}
+
+	// ShiftConfig configures a shift of the calendar.
+	ShiftConfig struct {
+		Name   string        `json:"name"`   // name of the shift, e.g. Early, defaults to Shift and its number
+		Start  string        `json:"start"`  // local start time, HH:MM
+		End    string        `json:"end"`    // local end time, HH:MM, the next day if not after the start
+		Days   []string      `json:"days"`   // weekdays the shift starts on, every working day if empty
+		Breaks []BreakConfig `json:"breaks"` // breaks during the shift
+	}
+
+	// BreakConfig configures a break during a shift.
+	BreakConfig struct {
+		Start    string        `json:"start"`    // local start time, HH:MM
+		Duration time.Duration `json:"duration"` // length of the break
+		Reason   string        `json:"reason"`   // downtime reason code, defaults to BREAK
+	}
+
+	// ShiftCalendar tells which shift works at a given time, and when the machines stop between shifts.
+	ShiftCalendar struct {
+		shifts         []calendarShift
+		nonWorkingDays []string
+		holidays       map[string]bool
+	}
+
+	// Shift is a shift of the calendar on a given day.
+	Shift struct {
+		Number int       // 1-based number of the shift in the calendar
+		ID     string    // unique ID of the shift, its start date and number, e.g. 2021-06-01-2
+		Name   string    // name of the shift
+		Start  time.Time // start of the shift
+		End    time.Time // end of the shift
+	}
+
+	calendarShift struct {
+		number int
+		name   string
+		start  int // minute of the day the shift starts
+		end    int // minute of the day the shift ends
+		days   []string
+		breaks []calendarBreak
+	}
+
+	calendarBreak struct {
+		start    int // minutes after the start of the shift
+		duration time.Duration
+		reason   string
+	}
+
+	// calendarStop is a time the machines do not produce, e.g. a break or a weekend.
+	calendarStop struct {
+		start  time.Time
+		end    time.Time
+		reason string
+	}
+)
+
+// NewShiftCalendar creates the shift calendar of a plant, or nil if the plant has no calendar configuration.
+func NewShiftCalendar(cfg *CalendarConfig) (*ShiftCalendar, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Shifts) == 0 {
+		return nil, fmt.Errorf("shift calendar needs shifts")
+	}
+	c := &ShiftCalendar{nonWorkingDays: cfg.NonWorkingDays, holidays: map[string]bool{}}
+	for i, shiftCfg := range cfg.Shifts {
+		shift := calendarShift{number: i + 1, name: shiftCfg.Name, days: shiftCfg.Days}
+		if shift.name == "" {
+			shift.name = fmt.Sprintf("Shift %d", shift.number)
+		}
+		var ok bool
+		if shift.start, ok = parseMinuteOfDay(shiftCfg.Start); !ok {
+			return nil, fmt.Errorf("shift %s has invalid start %s", shift.name, shiftCfg.Start)
+		}
+		if shift.end, ok = parseMinuteOfDay(shiftCfg.End); !ok {
+			return nil, fmt.Errorf("shift %s has invalid end %s", shift.name, shiftCfg.End)
+		}
+		length := shift.length()
+		for _, breakCfg := range shiftCfg.Breaks {
+			start, ok := parseMinuteOfDay(breakCfg.Start)
+			if !ok || breakCfg.Duration <= 0 {
+				return nil, fmt.Errorf("shift %s has invalid break %s", shift.name, breakCfg.Start)
+			}
+			b := calendarBreak{start: (start - shift.start + 24*60) % (24 * 60), duration: breakCfg.Duration, reason: breakCfg.Reason}
+			if time.Duration(b.start)*time.Minute+b.duration > time.Duration(length)*time.Minute {
+				return nil, fmt.Errorf("break %s is not within shift %s", breakCfg.Start, shift.name)
+			}
+			if b.reason == "" {
+				b.reason = ReasonBreak
+			}
+			shift.breaks = append(shift.breaks, b)
+		}
+		sort.Slice(shift.breaks, func(i, j int) bool { return shift.breaks[i].start < shift.breaks[j].start })
+		c.shifts = append(c.shifts, shift)
+	}
+	for _, holiday := range cfg.Holidays {
+		date, err := time.Parse("2006-01-02", holiday)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %s", holiday)
+		}
+		c.holidays[date.Format("2006-01-02")] = true
+	}
+	return c, nil
+}
+
+// Shift gets the shift working at the given time, or nil if no shift is working.
+func (c *ShiftCalendar) Shift(at time.Time) *Shift {
+	// a shift that runs past midnight started the day before
+	for day := -1; day <= 0; day++ {
+		for _, shift := range c.shiftsOn(at.AddDate(0, 0, day)) {
+			if !shift.Start.After(at) && shift.End.After(at) {
+				return shift
+			}
+		}
+	}
+	return nil
+}
+
+// nextStop gets the first time the machines do not produce that is active at or starts after the given time.
+func (c *ShiftCalendar) nextStop(after time.Time) (calendarStop, bool) {
+	if c == nil {
+		return calendarStop{}, false
+	}
+	// start two days back, so that a shift that started the day before is not taken for off time
+	from := startOfDay(after.AddDate(0, 0, -2))
+	until := startOfDay(after.AddDate(0, 0, calendarHorizon))
+	var stops []calendarStop
+	cursor := from
+	for day := from; day.Before(until); day = day.AddDate(0, 0, 1) {
+		for _, shift := range c.shiftsOn(day) {
+			if shift.Start.After(cursor) {
+				stops = append(stops, c.offTime(cursor, shift.Start)...)
+			}
+			for _, b := range c.shifts[shift.Number-1].breaks {
+				start := shift.Start.Add(time.Duration(b.start) * time.Minute)
+				stops = append(stops, calendarStop{start: start, end: start.Add(b.duration), reason: b.reason})
+			}
+			cursor = maxTime(cursor, shift.End)
+		}
+	}
+	stops = append(stops, c.offTime(cursor, until)...)
+	for _, stop := range stops {
+		if stop.end.After(after) {
+			return stop, true
+		}
+	}
+	return calendarStop{}, false
+}
+
+// offTime splits the time between two shifts at the days it crosses, as they may not work for different reasons.
+func (c *ShiftCalendar) offTime(from time.Time, to time.Time) []calendarStop {
+	var stops []calendarStop
+	for start := from; start.Before(to); {
+		end := startOfDay(start.AddDate(0, 0, 1))
+		if end.After(to) {
+			end = to
+		}
+		reason := c.dayOffReason(start)
+		if reason == "" {
+			reason = ReasonNoShift
+		}
+		if n := len(stops); n > 0 && stops[n-1].reason == reason {
+			stops[n-1].end = end
+		} else {
+			stops = append(stops, calendarStop{start: start, end: end, reason: reason})
+		}
+		start = end
+	}
+	return stops
+}
+
+// shiftsOn gets the shifts that start on the day of the given time, in the order of their start.
+func (c *ShiftCalendar) shiftsOn(at time.Time) []*Shift {
+	if c.dayOffReason(at) != "" {
+		return nil
+	}
+	var shifts []*Shift
+	for i := range c.shifts {
+		shift := &c.shifts[i]
+		if !appliesToDay(shift.days, at.Weekday()) {
+			continue
+		}
+		start := time.Date(at.Year(), at.Month(), at.Day(), shift.start/60, shift.start%60, 0, 0, at.Location())
+		end := time.Date(at.Year(), at.Month(), at.Day(), shift.end/60, shift.end%60, 0, 0, at.Location())
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		shifts = append(shifts, &Shift{
+			Number: shift.number,
+			ID:     fmt.Sprintf("%s-%d", start.Format("2006-01-02"), shift.number),
+			Name:   shift.name,
+			Start:  start,
+			End:    end,
+		})
+	}
+	sort.Slice(shifts, func(i, j int) bool { return shifts[i].Start.Before(shifts[j].Start) })
+	return shifts
+}
+
+// dayOffReason gets why the plant does not work on the day of the given time, or empty if it is a working day.
+func (c *ShiftCalendar) dayOffReason(at time.Time) string {
+	if c.holidays[at.Format("2006-01-02")] {
+		return ReasonHoliday
+	}
+	if len(c.nonWorkingDays) > 0 && appliesToDay(c.nonWorkingDays, at.Weekday()) {
+		return ReasonNonWorkingDay
+	}
+	return ""
+}
+
+// length gets the length of the shift in minutes, a full day if it ends at its start time.
+func (s *calendarShift) length() int {
+	length := (s.end - s.start + 24*60) % (24 * 60)
+	if length == 0 {
+		length = 24 * 60
+	}
+	return length
+}
+
+func startOfDay(at time.Time) time.Time {
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+}
@@ -0,0 +1,73 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+const defaultCarbonIntensity = 300.0 // gCO2/kWh of a plant without a carbon configuration, about the European average
+
+type (
+	// CarbonConfig configures the carbon intensity of the grid a plant draws its power from.
+	CarbonConfig struct {
+		File      string   `json:"file"`      // CSV file of hourly intensities in gCO2/kWh, by timestamp or hour of the day
+		Intensity *float64 `json:"intensity"` // intensity in gCO2/kWh of the hours the file does not cover, defaults to 300
+	}
+
+	// CarbonProfile gets the carbon intensity of the grid of a plant over time.
+	CarbonProfile struct {
+		series   *hourlySeries // intensities read from the file, if any
+		fallback float64
+	}
+)
+
+// NewCarbonProfile reads the carbon intensity file of a plant, see readHourlySeries.
+func NewCarbonProfile(cfg *CarbonConfig) (*CarbonProfile, error) {
+	p := &CarbonProfile{fallback: defaultCarbonIntensity}
+	if cfg == nil {
+		return p, nil
+	}
+	if cfg.Intensity != nil {
+		p.fallback = *cfg.Intensity
+	}
+	if cfg.File != "" {
+		series, err := readHourlySeries(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read carbon intensity file %s. %w", cfg.File, err)
+		}
+		p.series = series
+	}
+	return p, nil
+}
+
+// Intensity gets the carbon intensity in gCO2/kWh at the given local time, from the file if it covers the hour, else the fallback.
+func (p *CarbonProfile) Intensity(at time.Time) float64 {
+	if p == nil {
+		return defaultCarbonIntensity
+	}
+	if intensity, ok := p.series.get(at); ok {
+		return intensity
+	}
+	return p.fallback
+}
+
+// getIntervalEnergy gets the energy in kWh a machine used over a step, from its kwh telemetry or else its average power.
+func getIntervalEnergy(telemetry models.Telemetry, interval time.Duration) (float64, bool) {
+	if kwh, ok := getFloatValue(telemetry["kwh"]); ok {
+		return kwh, true
+	}
+	for _, name := range []string{"PowerUsage", "powerUsage"} {
+		if power, ok := getFloatValue(telemetry[name]); ok {
+			return power * interval.Hours(), true
+		}
+	}
+	return 0, false
+}
+
+// getEmissions gets the grams of CO2 emitted for the energy used.
+func getEmissions(kwh float64, intensity float64) float64 {
+	return math.Round(kwh*intensity*100) / 100
+}
@@ -0,0 +1,25 @@
+package simulating
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCarbonKeepsZeroIntensity(t *testing.T) {
+	zero := 0.0
+	at := time.Date(2022, 5, 24, 9, 0, 0, 0, time.UTC)
+	carbon, err := NewCarbonProfile(&CarbonConfig{Intensity: &zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intensity := carbon.Intensity(at); intensity != 0 {
+		t.Errorf("Intensity = %v, want 0", intensity)
+	}
+	carbon, err = NewCarbonProfile(&CarbonConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intensity := carbon.Intensity(at); intensity != defaultCarbonIntensity {
+		t.Errorf("Intensity = %v, want %v", intensity, defaultCarbonIntensity)
+	}
+}
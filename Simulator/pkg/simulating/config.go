@@ -1,9 +1,240 @@
 package simulating
 
-type Plant struct {
-	Name        string `json:"name"`
-	BoltMachine struct {
-		Count  int    `json:"count"`
-		Format string `json:"format"`
-	} `json:"BoltMachine"`
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+type (
+	Plant struct {
+		Name     string                 `json:"name"`
+		Lines    []LineConfig           `json:"lines"`                    // production lines linking the machines of the plant
+		Seed     int64                  `json:"seed"`                     // seed of the random sources of the plant's devices, overrides the global seed
+		Carbon   *CarbonConfig          `json:"carbon"`                   // carbon intensity of the grid the plant draws its power from
+		Tariff   *TariffConfig          `json:"tariff"`                   // electricity tariff the plant pays for its energy
+		Meter    *MeterConfig           `json:"meter"`                    // main energy meter of the plant, none if not set
+		Floors   []FloorConfig          `json:"floors"`                   // factory floors with a climate sensor each
+		Outdoor  *OutdoorConfig         `json:"outdoor"`                  // weather at the plant, defaults apply if not set
+		Calendar *CalendarConfig        `json:"calendar"`                 // shifts the plant works, around the clock if not set
+		Timezone string                 `json:"timezone"`                 // IANA time zone of the plant, e.g. America/Chicago, defaults to UTC
+		Products []ProductConfig        `json:"products"`                 // product catalogue of the plant
+		Plan     []PlanConfig           `json:"plan"`                     // products the machines make, one per batch
+		Machines map[string]interface{} `json:"-" mapstructure:",remain"` // machine blocks keyed by machine kind, e.g. boltMachine
+	}
+
+	MachineConfig struct {
+		Count            int                `json:"count"`            // number of machines of this kind in the plant
+		Format           string             `json:"format"`           // telemetry payload format, json or opcua
+		StandbyFrequency int                `json:"standbyFrequency"` // seconds between standby messages of a switched off machine, defaults to 300
+		State            *StateConfig       `json:"state"`            // state machine of the machines, defaults apply if not set
+		Energy           *EnergyConfig      `json:"energy"`           // energy model of the machines, defaults apply if not set
+		Maintenance      *MaintenanceConfig `json:"maintenance"`      // oil consumption and maintenance of the machines, defaults apply if not set
+		Wear             *WearConfig        `json:"wear"`             // tool wear of the machines, defaults apply if not set
+		Load             *LoadConfig        `json:"load"`             // daily and weekly load profile of the machines, full load if not set
+		Utilities        []UtilityConfig    `json:"utilities"`        // utilities the machines consume next to electricity, e.g. compressed air
+
+		alwaysOn bool // the device measures the plant, which it keeps doing between the shifts
+	}
+)
+
+// MachineConfig gets the configuration block of the given machine kind, or nil if the plant has none.
+func (p *Plant) MachineConfig(kind string) (*MachineConfig, error) {
+	for name, block := range p.Machines {
+		if !strings.EqualFold(name, kind) {
+			continue
+		}
+		// a partial energy, maintenance or wear block only changes the values it sets
+		cfg := MachineConfig{Energy: defaultEnergyConfig(kind), Maintenance: defaultMaintenanceConfig(), Wear: defaultWearConfig()}
+		if err := decodeConfig(block, &cfg); err != nil {
+			return nil, err
+		}
+		for i := range cfg.Utilities {
+			if err := cfg.Utilities[i].applyDefaults(); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.State != nil {
+			if err := cfg.State.validate(); err != nil {
+				return nil, fmt.Errorf("invalid state configuration of machine %s. %w", name, err)
+			}
+		}
+		if cfg.Load != nil {
+			if err := cfg.Load.validate(); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.Wear != nil {
+			if err := cfg.Wear.validate(); err != nil {
+				return nil, fmt.Errorf("invalid wear configuration of machine %s. %w", name, err)
+			}
+		}
+		return &cfg, nil
+	}
+	return nil, nil
+}
+
+// Location gets the time zone of the plant, which its shifts and daily schedules follow.
+func (p *Plant) Location() (*time.Location, error) {
+	if p.Timezone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %s", p.Timezone)
+	}
+	return location, nil
+}
+
+// ProductionLines creates the production lines of the plant, checking that every step is a machine of the plant.
+func (p *Plant) ProductionLines() ([]*ProductionLine, error) {
+	var lines []*ProductionLine
+	used := map[string]string{}
+	for i := range p.Lines {
+		line, err := NewProductionLine(&p.Lines[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, step := range line.steps {
+			cfg, err := p.MachineConfig(step.cfg.Machine)
+			if err != nil {
+				return nil, err
+			}
+			if cfg == nil || step.cfg.Index > cfg.Count {
+				return nil, fmt.Errorf("production line %s has unknown machine %s %d", line.name, step.cfg.Machine, step.cfg.Index)
+			}
+			key := fmt.Sprintf("%s-%d", strings.ToLower(step.cfg.Machine), step.cfg.Index)
+			if other, ok := used[key]; ok {
+				return nil, fmt.Errorf("machine %s %d is in production lines %s and %s", step.cfg.Machine, step.cfg.Index, other, line.name)
+			}
+			used[key] = line.name
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// FactoryFloors creates the floors of the plant in its weather, checking that every machine on a floor is a machine of
+// the plant.
+func (p *Plant) FactoryFloors(weather *Weather) ([]*Floor, error) {
+	var floors []*Floor
+	used := map[string]string{}
+	for i := range p.Floors {
+		cfg := &p.Floors[i]
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("floor %d needs a name", i+1)
+		}
+		if len(cfg.Machines) == 0 && len(p.Floors) > 1 {
+			return nil, fmt.Errorf("floor %s needs machines as the plant has more than one floor", cfg.Name)
+		}
+		for _, machine := range cfg.Machines {
+			if machine.Index == 0 {
+				machine.Index = 1
+			}
+			machineCfg, err := p.MachineConfig(machine.Machine)
+			if err != nil {
+				return nil, err
+			}
+			if machineCfg == nil || machine.Index > machineCfg.Count {
+				return nil, fmt.Errorf("floor %s has unknown machine %s %d", cfg.Name, machine.Machine, machine.Index)
+			}
+			key := machineKey(machine.Machine, machine.Index)
+			if other, ok := used[key]; ok {
+				return nil, fmt.Errorf("machine %s %d is on floors %s and %s", machine.Machine, machine.Index, other, cfg.Name)
+			}
+			used[key] = cfg.Name
+		}
+		floors = append(floors, NewFloor(cfg, weather))
+	}
+	return floors, nil
+}
+
+// ProductionPlan gets the products every machine of the plant makes in turn, keyed by machine kind and index,
+// checking that every product and machine in the plan is known.
+func (p *Plant) ProductionPlan() (ProductionPlan, error) {
+	products := map[string]*ProductConfig{}
+	for i := range p.Products {
+		product := &p.Products[i]
+		if product.ID == "" {
+			return nil, fmt.Errorf("product %d needs an id", i+1)
+		}
+		if _, ok := products[product.ID]; ok {
+			return nil, fmt.Errorf("product %s is in the catalogue twice", product.ID)
+		}
+		if product.CycleTime <= 0 {
+			return nil, fmt.Errorf("product %s needs a cycle time", product.ID)
+		}
+		if product.DefectRate < 0 || product.DefectRate > 1 {
+			return nil, fmt.Errorf("product %s has a defect rate outside 0 to 1", product.ID)
+		}
+		products[product.ID] = product
+	}
+
+	plan := ProductionPlan{}
+	for _, entry := range p.Plan {
+		cfg, err := p.MachineConfig(entry.Machine)
+		if err != nil {
+			return nil, err
+		}
+		if cfg == nil || entry.Index > cfg.Count {
+			return nil, fmt.Errorf("production plan has unknown machine %s %d", entry.Machine, entry.Index)
+		}
+		if len(entry.Products) == 0 {
+			return nil, fmt.Errorf("production plan of machine %s needs products", entry.Machine)
+		}
+		var sequence []*ProductConfig
+		for _, id := range entry.Products {
+			product, ok := products[id]
+			if !ok {
+				return nil, fmt.Errorf("production plan of machine %s has unknown product %s", entry.Machine, id)
+			}
+			sequence = append(sequence, product)
+		}
+		// an entry for a single machine overrides the entry for its kind
+		for i := 1; i <= cfg.Count; i++ {
+			if entry.Index == 0 || entry.Index == i {
+				key := machineKey(entry.Machine, i)
+				if _, ok := plan[key]; !ok || entry.Index != 0 {
+					plan[key] = sequence
+				}
+			}
+		}
+	}
+	return plan, nil
+}
+
+// UnknownMachineKinds gets the names of the plant's configuration blocks that do not match a registered machine kind.
+func (p *Plant) UnknownMachineKinds() []string {
+	var unknown []string
+	for name := range p.Machines {
+		found := false
+		for _, kind := range MachineKinds() {
+			if strings.EqualFold(name, kind) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// decodeConfig decodes a raw configuration block the same way viper decodes the configuration file.
+func decodeConfig(input interface{}, output interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           output,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
 }
@@ -0,0 +1,102 @@
+package simulating
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+func TestPartialWearBlock(t *testing.T) {
+	plant := &Plant{Machines: map[string]interface{}{
+		"boltMachine": map[string]interface{}{"count": 1, "wear": map[string]interface{}{"exportRul": true}},
+	}}
+	cfg, err := plant.MachineConfig("boltMachine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := defaultWearConfig()
+	want.ExportRul = true
+	if *cfg.Wear != *want {
+		t.Errorf("Wear = %+v, want %+v", *cfg.Wear, *want)
+	}
+
+	// the machine reports its wear and remaining life with the default tool life
+	machine, err := newBoltMachine(&MachineSpec{Config: cfg, Rand: NewDeviceRand(1, "Test-BoltMachine-1"), App: &models.CentralApplication{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 5, 24, 9, 0, 0, 0, time.UTC)
+	tick := &Tick{
+		Now:          now,
+		Local:        now,
+		Interval:     10 * time.Minute,
+		Load:         1,
+		State:        StateRunning,
+		RunTime:      10 * time.Minute,
+		StateMachine: NewStateMachine(nil, nil, time.UTC, now.Add(-10*time.Minute), machine.(*boltMachine).rand),
+	}
+	if _, err := machine.NextTelemetry(tick); err != nil {
+		t.Fatal(err)
+	}
+	if state := tick.StateMachine.Current(); state != StateRunning {
+		t.Errorf("state = %s, want %s", state, StateRunning)
+	}
+}
+
+func TestInvalidWearBlock(t *testing.T) {
+	for _, wear := range []map[string]interface{}{
+		{"toolLife": 0},
+		{"changeAt": 1.5},
+		{"repairDuration": "-1h"},
+	} {
+		plant := &Plant{Machines: map[string]interface{}{"boltMachine": map[string]interface{}{"wear": wear}}}
+		if _, err := plant.MachineConfig("boltMachine"); err == nil {
+			t.Errorf("MachineConfig with wear %v = nil error, want an error", wear)
+		}
+	}
+}
+
+func TestPartialEnergyBlock(t *testing.T) {
+	plant := &Plant{Machines: map[string]interface{}{
+		"boltMachine": map[string]interface{}{
+			"energy":      map[string]interface{}{"standbyPower": 0.8},
+			"maintenance": map[string]interface{}{"scheduleEvery": "8h"},
+		},
+	}}
+	cfg, err := plant.MachineConfig("boltMachine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	energy := defaultEnergyConfig("boltMachine")
+	energy.StandbyPower = 0.8
+	if *cfg.Energy != *energy {
+		t.Errorf("Energy = %+v, want %+v", *cfg.Energy, *energy)
+	}
+	maintenance := defaultMaintenanceConfig()
+	maintenance.ScheduleEvery = 8 * time.Hour
+	if *cfg.Maintenance != *maintenance {
+		t.Errorf("Maintenance = %+v, want %+v", *cfg.Maintenance, *maintenance)
+	}
+}
+
+func TestZeroStandbyPower(t *testing.T) {
+	plant := &Plant{Machines: map[string]interface{}{
+		"grindingMachine": map[string]interface{}{"energy": map[string]interface{}{"standbyPower": 0}},
+		"mouldingMachine": map[string]interface{}{"count": 1},
+	}}
+	grinding, err := plant.MachineConfig("grindingMachine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if power := getStandbyPower(grinding, grindingStandbyPower); power != 0 {
+		t.Errorf("grinding standby power = %v, want 0", power)
+	}
+	moulding, err := plant.MachineConfig("mouldingMachine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if power := getStandbyPower(moulding, mouldingStandbyPower); power != mouldingStandbyPower {
+		t.Errorf("moulding standby power = %v, want %v", power, mouldingStandbyPower)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"net"
 	"os"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/amenzhinsky/iothub/iotdevice"
@@ -22,6 +25,7 @@ import (
 type (
 	centralDevice struct {
 		deviceID                    string // unique id of the device.
+		plantName                   string // plant the machine belongs to.
 		app                         *models.CentralApplication
 		context                     context.Context
 		cancel                      context.CancelFunc
@@ -30,7 +34,32 @@ type (
 		reportedPropertiesFrequency int                     // Twin property - how often this device should send reported properties
 		shiftDurationHours          int                     // Twin property - how many hours are there in an employee shift
 		batchDurationHours          int                     // Twin property - how many hours are there in batch
-		boltMachine                 *models.BoltMachine     // bolt machine state
+		machine                     Machine                 // simulated machine
+		machineMutex                sync.Mutex              // guards the machine against commands and properties that arrive while it steps
+		config                      *MachineConfig          // machine configuration block of the plant
+		line                        *LineStep               // step of the production line the machine belongs to
+		state                       *StateMachine           // operating state of the machine
+		lastTick                    time.Time               // time of the previous simulation step
+		offSince                    time.Time               // time the machine was switched off
+		rand                        *rand.Rand              // random source of the device
+		scenario                    *Scenario               // scenario of injected faults and events
+		scenarioEvents              []*ScenarioEvent        // scenario events active in the current step
+		scenarioHold                bool                    // a scenario event holds the machine in a downtime state
+		outage                      bool                    // a scenario power outage keeps the device silent
+		labels                      *AnomalyLabels          // ground truth of the anomalies the device reports
+		carbon                      *CarbonProfile          // carbon intensity of the grid of the plant
+		tariff                      *Tariff                 // electricity tariff of the plant
+		meter                       *PlantMeter             // plant meter the energy of the machine adds up in
+		floor                       *Floor                  // floor the machine gives off its heat to
+		weather                     *Weather                // weather at the plant
+		calendar                    *ShiftCalendar          // shift calendar of the plant
+		location                    *time.Location          // time zone of the plant
+		products                    []*ProductConfig        // products the machine makes in turn, a batch each
+		shiftID                     string                  // shift the shift energy cost is totalled for
+		shiftEnergyCost             float64                 // energy cost since the start of the shift
+		batchID                     string                  // batch the batch energy cost is totalled for
+		batchEnergyCost             float64                 // energy cost since the start of the batch
+		oee                         oeeTracker              // OEE of the machine over the shift, the batch and the last hour
 		provisioner                 *DeviceProvisioner      // provisioner used to provision the device in DPS
 		connectionString            string                  // IoT Hub connectionString of the device.
 		isConnected                 bool                    // is the device connected.
@@ -49,15 +78,19 @@ type (
 	}
 )
 
-func NewDevice(ctx context.Context, app *models.CentralApplication, deviceID string,
-	boltMachine *models.BoltMachine) *centralDevice {
+func NewDevice(ctx context.Context, spec *MachineSpec, machine Machine) *centralDevice {
+	location := spec.Location
+	if location == nil {
+		location = time.UTC
+	}
 	deviceCtx, cancel := context.WithCancel(ctx)
 	twCtx, twCancel := context.WithCancel(deviceCtx)
 	rwCtx, rwCancel := context.WithCancel(deviceCtx)
 
 	return &centralDevice{
-		deviceID:                    deviceID,
-		app:                         app,
+		deviceID:                    spec.DeviceID,
+		plantName:                   spec.PlantName,
+		app:                         spec.App,
 		context:                     deviceCtx,
 		cancel:                      cancel,
 		isMachineOn:                 true,
@@ -65,8 +98,21 @@ func NewDevice(ctx context.Context, app *models.CentralApplication, deviceID str
 		reportedPropertiesFrequency: 60 * 60 * 2,
 		shiftDurationHours:          8,
 		batchDurationHours:          1,
-		boltMachine:                 boltMachine,
-		provisioner:                 NewProvisioner(deviceCtx, app),
+		machine:                     machine,
+		config:                      spec.Config,
+		line:                        spec.Line,
+		rand:                        spec.Rand,
+		scenario:                    spec.Scenario,
+		labels:                      spec.Labels,
+		carbon:                      spec.Carbon,
+		tariff:                      spec.Tariff,
+		meter:                       spec.Meter,
+		floor:                       spec.Floor,
+		weather:                     spec.Weather,
+		calendar:                    spec.Calendar,
+		location:                    location,
+		products:                    spec.Products,
+		provisioner:                 NewProvisioner(deviceCtx, spec.App),
 		connectionString:            "",
 		isConnected:                 false,
 		isConnecting:                false,
@@ -114,25 +160,28 @@ func (d *centralDevice) startTelemetryPump() {
 			// send telemetry
 			log.Trace().Str("deviceID", d.deviceID).Msg("send telemetry")
 
-			// send telemetry only if the machine is ON
-			if d.isMachineOn {
-				telemetry, err := d.getTelemetryMessage()
-				if err != nil {
-					log.Error().Err(err).Str("deviceID", d.deviceID).Msg("error preparing telemetry from host")
-				} else {
-					if d.sendTelemetryMessage(telemetry) {
-						log.Debug().Str("payload", string(telemetry)).Msg("sent telemetry")
-					}
-				}
+			// a machine that is OFF only sends its standby energy, at a lower rate
+			frequency := d.telemetryFrequency
+			if !d.isMachineOn {
+				frequency = d.getStandbyFrequency()
+				log.Debug().Str("deviceID", d.deviceID).Msg("sending standby telemetry as the machine is OFF")
+			}
+			telemetry, err := d.getTelemetryMessage()
+			if err != nil {
+				log.Error().Err(err).Str("deviceID", d.deviceID).Msg("error preparing telemetry from host")
+			} else if telemetry == nil {
+				log.Debug().Str("deviceID", d.deviceID).Msg("ignoring telemetry during a power outage")
 			} else {
-				log.Debug().Str("deviceID", d.deviceID).Msg("ignoring telemetry as the machine is OFF")
+				if d.sendTelemetryMessage(telemetry) {
+					log.Debug().Str("payload", string(telemetry)).Msg("sent telemetry")
+				}
 			}
 
 			// sleep for some time between each telemetry sends
 			select {
 			case <-d.telemetryWaitContext.Done():
 				return
-			case <-time.After(time.Second * time.Duration(d.telemetryFrequency)):
+			case <-time.After(time.Second * time.Duration(frequency)):
 			}
 		}
 	}
@@ -165,80 +214,269 @@ func (d *centralDevice) startReportedPropsPump() {
 }
 
 func (d *centralDevice) getTelemetryMessage() ([]byte, error) {
+	tick := d.getTick()
+	if d.outage {
+		// the machine does not report, but the outage still counts against its availability
+		d.addOEE(tick, models.Telemetry{})
+		d.labelAnomalies(tick.Now, nil)
+		return nil, nil
+	}
+	d.machineMutex.Lock()
+	telemetry, err := d.machine.NextTelemetry(tick)
+	d.machineMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if tick.Line != nil {
+		tick.Line.Move(tick.LineOutput, tick.Now)
+	}
 
-	now := time.Now().UTC()
-	shiftNumber := now.Hour() / d.shiftDurationHours
-	if now.After(time.Date(now.Year(), now.Month(), now.Day(), shiftNumber*d.shiftDurationHours, 0, 0, 0, time.UTC)) {
-		shiftNumber++
+	// every machine reports its operating state, which it may have changed itself
+	if tick.State != StateOff {
+		tick.State = d.state.Current()
+	}
+	telemetry["machineState"] = tick.State
+	if tick.State == StateOff {
+		telemetry["stateDuration"] = int(tick.Now.Sub(d.offSince).Seconds())
+		telemetry["downtimeReason"] = ReasonSwitchedOff
+	} else {
+		telemetry["stateDuration"] = int(d.state.Duration(tick.Now).Seconds())
+		telemetry["downtimeReason"] = d.state.Reason()
+	}
+	telemetry["shiftId"] = tick.ShiftID
+	telemetry["shiftName"] = tick.ShiftName
+	telemetry["batchId"] = tick.BatchID
+	if tick.Product != nil {
+		telemetry["productId"] = tick.Product.ID
+	}
+	if d.line != nil {
+		telemetry["bufferLevel"] = d.line.BufferLevel()
+		telemetry["lineThroughput"] = d.line.Throughput(tick.Now)
+	}
+	if !d.config.alwaysOn {
+		telemetry["ambientTemperature"] = math.Round(tick.Ambient*10) / 10
 	}
 
-	batchNumber := now.Hour() / d.batchDurationHours
-	if now.After(time.Date(now.Year(), now.Month(), now.Day(), batchNumber*d.batchDurationHours, 0, 0, 0, time.UTC)) {
-		batchNumber++
+	// injected events change the values the machine reports, so that the values derived from them follow
+	for _, event := range d.scenarioEvents {
+		// an event for the whole plant already changed the values of the machines the device adds up
+		if aggregator, ok := d.machine.(Aggregator); ok && !strings.EqualFold(event.Target, d.deviceID) && aggregator.Aggregates(event.Field) {
+			continue
+		}
+		event.apply(telemetry)
 	}
 
-	totalPartsMade := 90 + rand.Intn(10)
-	defectivePartsMade := 0
-	if rand.Intn(100) > 80 {
-		defectivePartsMade = rand.Intn(10)
+	// the emissions and cost follow the energy the machine used over the step
+	if kwh, ok := getIntervalEnergy(telemetry, tick.Interval); ok {
+		telemetry["co2grams"] = getEmissions(kwh, d.carbon.Intensity(tick.Local))
+		d.addEnergyCost(tick, telemetry, kwh)
+		d.meter.Add(kwh)
+		d.floor.AddHeat(kwh)
 	}
+	d.addUtilities(tick, telemetry)
+	d.addOEE(tick, telemetry)
+	d.oee.addTelemetry(telemetry)
+	d.labelAnomalies(tick.Now, telemetry)
 
-	d.boltMachine.OilLevel -= 0.1
-	if d.boltMachine.OilLevel <= 0.0 {
-		d.boltMachine.OilLevel = 100.0
+	return d.getTelemetryPayload(telemetry)
+}
+
+// addEnergyCost adds the cost of the energy used over the step at the tariff of the plant, and its totals over the
+// shift and the batch.
+func (d *centralDevice) addEnergyCost(tick *Tick, telemetry models.Telemetry, kwh float64) {
+	cost := getEnergyCost(kwh, d.tariff.Price(tick.Local))
+	if tick.ShiftID != d.shiftID {
+		d.shiftID = tick.ShiftID
+		d.shiftEnergyCost = 0
+	}
+	if tick.BatchID != d.batchID {
+		d.batchID = tick.BatchID
+		d.batchEnergyCost = 0
 	}
+	d.shiftEnergyCost += cost
+	d.batchEnergyCost += cost
+	telemetry["energyCost"] = cost
+	telemetry["shiftEnergyCost"] = math.Round(d.shiftEnergyCost*10000) / 10000
+	telemetry["batchEnergyCost"] = math.Round(d.batchEnergyCost*10000) / 10000
+}
 
-	if d.boltMachine.OilLevel < 10.0 {
-		d.boltMachine.MachineHealth = "Error"
-		totalPartsMade = 0
-		defectivePartsMade = 0
-	} else if d.boltMachine.OilLevel < 25.0 {
-		d.boltMachine.MachineHealth = "Warning"
-		totalPartsMade -= 50
-	} else {
-		d.boltMachine.MachineHealth = "Healthy"
+// addUtilities adds the consumption of the utilities the machine uses next to electricity.
+func (d *centralDevice) addUtilities(tick *Tick, telemetry models.Telemetry) {
+	if len(d.config.Utilities) == 0 {
+		return
 	}
+	parts := getPartsMade(tick, telemetry)
+	utilities := map[string]UtilityReading{}
+	for i := range d.config.Utilities {
+		utility := &d.config.Utilities[i]
+		utilities[utility.Name] = utility.getReading(tick.RunTime, tick.Interval-tick.RunTime, parts, tick.Ambient, d.rand)
+	}
+	telemetry["utilities"] = utilities
+}
 
-	if d.boltMachine.Temperature >= 90 {
-		d.boltMachine.Temperature -= 0.5
-	} else if d.boltMachine.Temperature <= 50 {
-		d.boltMachine.Temperature += 0.5
-	} else {
-		if rand.Intn(100) > 50 {
-			d.boltMachine.Temperature += 0.5
-		} else {
-			d.boltMachine.Temperature -= 0.5
+// addOEE adds the step to the OEE of the machine. Devices that measure the plant have no OEE.
+func (d *centralDevice) addOEE(tick *Tick, telemetry models.Telemetry) {
+	if d.config.alwaysOn {
+		return
+	}
+	cycleTime := time.Duration(0)
+	if counter, ok := d.machine.(PartCounter); ok {
+		cycleTime = counter.IdealCycleTime(tick)
+	}
+	d.oee.add(tick, telemetry, cycleTime)
+}
+
+// labelAnomalies records the injected events, failures and degradation in the telemetry sent at the given time.
+func (d *centralDevice) labelAnomalies(now time.Time, telemetry models.Telemetry) {
+	if d.labels == nil {
+		return
+	}
+	var anomalies []AnomalyLabel
+	for _, event := range d.scenarioEvents {
+		anomaly := AnomalyLabel{Type: event.Type, Signal: event.Field, Reason: event.Reason}
+		switch event.Type {
+		case ScenarioOutage:
+			anomaly.Signal = "*"
+			anomaly.Reason = ReasonPowerOutage
+		case ScenarioFault:
+			anomaly.Signal = "machineState"
+			if anomaly.Reason == "" {
+				anomaly.Reason = ReasonMachineFailure
+			}
 		}
+		anomalies = append(anomalies, anomaly)
 	}
-	//added Remco
-	if d.boltMachine.Kwh >= 91 {
-		d.boltMachine.Kwh -= 0.5
-	} else if d.boltMachine.Kwh <= 89 {
-		d.boltMachine.Kwh += 0.5
-	} else {
-		if rand.Intn(100) > 40 {
-			d.boltMachine.Kwh += 0.5
-		} else {
-			d.boltMachine.Kwh -= 0.5
+	if telemetry != nil {
+		// failures injected by the scenario are labelled as scenario events
+		if telemetry["machineState"] == StateUnplannedDowntime && !d.scenarioHold {
+			anomalies = append(anomalies, AnomalyLabel{Type: AnomalyFailure, Signal: "machineState", Reason: d.state.Reason()})
+		}
+		if health, ok := telemetry["machineHealth"].(string); ok && health != "Healthy" {
+			anomalies = append(anomalies, AnomalyLabel{Type: AnomalyDegradation, Signal: "machineHealth", Reason: health})
+		}
+	}
+	d.labels.Update(d.deviceID, anomalies, now)
+}
+
+// getTick gets the next simulation step of the machine.
+func (d *centralDevice) getTick() *Tick {
+	now := time.Now().UTC()
+	// shifts and batches follow the local time of the plant, the timestamps stay in UTC
+	local := now.In(d.location)
+	batchNumber := local.Hour()/d.batchDurationHours + 1
+
+	interval := time.Second * time.Duration(d.telemetryFrequency)
+	if !d.lastTick.IsZero() {
+		interval = now.Sub(d.lastTick)
+	}
+	d.lastTick = now
+
+	// the state machine starts running when the first step is simulated
+	if d.state == nil {
+		calendar := d.calendar
+		if d.config.alwaysOn {
+			calendar = nil
+		}
+		d.state = NewStateMachine(d.config.State, calendar, d.location, now.Add(-interval), d.rand)
+	}
+
+	tick := &Tick{
+		Now:          now,
+		Local:        local,
+		Interval:     interval,
+		BatchNumber:  batchNumber,
+		BatchID:      fmt.Sprintf("%s-%d", local.Format("2006-01-02"), batchNumber),
+		Product:      getBatchProduct(d.products, local, batchNumber, d.batchDurationHours),
+		StateMachine: d.state,
+	}
+	shift := d.getShift(local)
+	if shift != nil {
+		tick.ShiftNumber = shift.Number
+		tick.ShiftID = shift.ID
+		tick.ShiftName = shift.Name
+	}
+	tick.Load = d.config.Load.getLoad(local, shift)
+	tick.Ambient = d.getAmbientTemperature(local)
+	d.applyScenario(now)
+	if d.isMachineOn {
+		tick.RunTime, tick.PlannedStop = d.state.Advance(now)
+
+		// a machine in a production line only produces what its buffers allow
+		if d.line != nil {
+			var constraint string
+			tick.Line = d.line
+			tick.LineOutput, tick.RunTime, constraint = d.line.Process(tick.RunTime, tick.Load, now)
+			d.state.Constrain(constraint, now)
 		}
+		tick.State = d.state.Current()
+	} else {
+		// a switched off machine does not run, fail or change over
+		d.state.Skip(now)
+		tick.State = StateOff
 	}
+	return tick
+}
 
-	telemetry := models.BoltMachineTelemetryMessage{
-		PlantName:          d.boltMachine.PlantName,
-		ProductionLine:     d.boltMachine.ProductionLine,
-		ShiftNumber:        shiftNumber,
-		BatchNumber:        batchNumber,
-		MessageTimestamp:   time.Now().UTC(),
-		TotalPartsMade:     totalPartsMade,
-		DefectivePartsMade: defectivePartsMade,
-		MachineHealth:      d.boltMachine.MachineHealth,
-		OilLevel:           d.boltMachine.OilLevel,
-		Temperature:        d.boltMachine.Temperature,
-		Kwh:                d.boltMachine.Kwh,
-		PlannedKwH:         d.boltMachine.PlannedKwH,
+// getShift gets the shift working at the given local time from the shift calendar of the plant, or else from the
+// shift duration property, counting the shifts from midnight.
+func (d *centralDevice) getShift(now time.Time) *Shift {
+	if d.calendar != nil {
+		return d.calendar.Shift(now)
 	}
+	number := now.Hour()/d.shiftDurationHours + 1
+	start := time.Date(now.Year(), now.Month(), now.Day(), (number-1)*d.shiftDurationHours, 0, 0, 0, now.Location())
+	return &Shift{
+		Number: number,
+		ID:     fmt.Sprintf("%s-%d", start.Format("2006-01-02"), number),
+		Name:   fmt.Sprintf("Shift %d", number),
+		Start:  start,
+		End:    start.Add(time.Duration(d.shiftDurationHours) * time.Hour),
+	}
+}
 
-	return d.getBoltTelemetryPayload(&telemetry, d.boltMachine.Format == "opcua")
+// getAmbientTemperature gets the temperature around the machine at the given local time: that of the floor it is on,
+// else that of a hall that is heated in the cold and gets as warm as the weather outside.
+func (d *centralDevice) getAmbientTemperature(local time.Time) float64 {
+	if d.floor != nil {
+		return d.floor.Temperature(local)
+	}
+	return math.Max(hallTemperature, d.weather.Temperature(local))
+}
+
+// applyScenario gets the scenario events active at the given time, and holds the machine down during outages and faults.
+func (d *centralDevice) applyScenario(now time.Time) {
+	d.scenarioEvents = d.scenario.activeEvents(d.deviceID, d.plantName, now)
+	d.outage = false
+	hold := false
+	for _, event := range d.scenarioEvents {
+		switch event.Type {
+		case ScenarioOutage:
+			d.outage = true
+			hold = true
+			d.state.HoldScenario(StateUnplannedDowntime, ReasonPowerOutage, now)
+		case ScenarioFault:
+			if !hold {
+				reason := event.Reason
+				if reason == "" {
+					reason = ReasonMachineFailure
+				}
+				hold = true
+				d.state.HoldScenario(StateUnplannedDowntime, reason, now)
+			}
+		}
+	}
+	if d.scenarioHold && !hold {
+		d.state.ReleaseScenario(now)
+	}
+	d.scenarioHold = hold
+}
+
+// getStandbyFrequency gets how often a switched off machine sends standby telemetry in seconds.
+func (d *centralDevice) getStandbyFrequency() int {
+	if d.config.StandbyFrequency > 0 {
+		return d.config.StandbyFrequency
+	}
+	return 300
 }
 
 func (d *centralDevice) sendTelemetryMessage(body []byte) bool {
@@ -500,15 +738,44 @@ func (d *centralDevice) unsubscribeTwinUpdates() bool {
 	return true
 }
 
+// handleCommand lets the machine execute a command, which arrives on the connection while the telemetry pump steps
+// the machine.
+func (d *centralDevice) handleCommand(command string, payload map[string]interface{}) (map[string]interface{}, error) {
+	d.machineMutex.Lock()
+	defer d.machineMutex.Unlock()
+	return d.machine.HandleCommand(command, payload)
+}
+
 // subscribeCommands subscribe for c2d command requests from IoT Central to the device
 func (d *centralDevice) subscribeCommands() bool {
 	// register for (Sync) Direct Methods
+	for _, name := range d.machine.Commands() {
+		command := name
+		timeoutCtx, cancel := context.WithTimeout(d.context, time.Millisecond*time.Duration(10000))
+		err := d.iotHubClient.RegisterMethod(timeoutCtx, command, func(payload map[string]interface{}) (int, map[string]interface{}, error) {
+			log.Debug().Str("deviceID", d.deviceID).Str("command", command).Msg("got command")
+			response, err := d.handleCommand(command, payload)
+			if err != nil {
+				log.Error().Err(err).Str("deviceID", d.deviceID).Str("command", command).Msg("command failed")
+				return 400, nil, err
+			}
+			return 200, response, nil
+		})
+		cancel()
+		if err != nil {
+			log.Err(err).Str("deviceID", d.deviceID).Str("command", command).Msg("command registration failed")
+			return false
+		}
+	}
 
 	return true
 }
 
 // unsubscribeCommands unsubscribe from c2d command requests for a given device
 func (d *centralDevice) unsubscribeCommands() bool {
+	for _, name := range d.machine.Commands() {
+		d.iotHubClient.UnregisterMethod(name)
+	}
 
 	return true
 }
@@ -520,7 +787,7 @@ func (d *centralDevice) provisionDevice() bool {
 		DeviceID:    d.deviceID,
 		Context:     d.context,
 		Application: d.app,
-		ModelID:     d.app.BoltMachineModelID,
+		ModelID:     d.machine.ModelID(),
 	}
 	result := d.provisioner.Provision(req)
 	if result == nil {
@@ -564,9 +831,29 @@ func (d *centralDevice) applyTwinUpdate(desiredTwin iotdevice.TwinState, forceUp
 			val, responseTwin, ok := d.getBoolTwinValue(key, value, desiredVersion)
 			if ok {
 				reportedTwin[key] = responseTwin
+				if !val && (d.isMachineOn || d.offSince.IsZero()) {
+					d.offSince = time.Now().UTC()
+				}
 				d.isMachineOn = val
 				deviceChanged = true
 			}
+		default:
+			// let the machine apply its own writable properties
+			if strings.HasPrefix(key, "$") {
+				continue
+			}
+			d.machineMutex.Lock()
+			applied := d.machine.ApplyProperty(key, value)
+			d.machineMutex.Unlock()
+			if applied {
+				reportedTwin[key] = map[string]interface{}{
+					"value": value,
+					"ac":    200,
+					"ad":    "completed",
+					"av":    desiredVersion,
+				}
+				deviceChanged = true
+			}
 		}
 	}
 
@@ -676,7 +963,7 @@ func (d *centralDevice) sleep(ctx context.Context, duration time.Duration) {
 
 // randSleep sleeps for random time within the given min/max range with cancellation context
 func (d *centralDevice) randSleep(ctx context.Context, minMs int, maxMs int) {
-	d.sleep(ctx, time.Millisecond*time.Duration(minMs+rand.Intn(maxMs)))
+	d.sleep(ctx, time.Millisecond*time.Duration(minMs+d.rand.Intn(maxMs)))
 }
 
 func (d *centralDevice) getHostName() string {
@@ -704,78 +991,69 @@ func (d *centralDevice) getLocalIP() string {
 	return ""
 }
 
-func (d *centralDevice) getBoltTelemetryPayload(tm *models.BoltMachineTelemetryMessage, opcua bool) ([]byte, error) {
-	if opcua {
-
-		// OPCUA device sending JSON payload
-		msgGuid, _ := uuid.GenerateUUID()
-		payload := make(map[string]interface{})
-		msgList := make([]map[string]interface{}, 1)
-		d.telemetrySequenceNumber++
-		msgList[0] = map[string]interface{}{
-			"DataSetWriterId": fmt.Sprintf("%s-%s", d.deviceID, msgGuid),
-			"MetaDataVersion": map[string]interface{}{
-				"MajorVersion": 1,
-				"MinorVersion": 0,
-			},
-			"SequenceNumber": d.telemetrySequenceNumber, //  rand.Intn(100000),
-			"Status":         nil,
-			"Timestamp":      d.getDateTime(),
-			"Payload":        payload,
-		}
-
-		eventId, _ := uuid.GenerateUUID()
-		telemetryValues := map[string]interface{}{
-			"DataSetClassId":     nil,
-			"DataSetWriterGroup": d.deviceID,
-			"EventId":            eventId,
-			"MessageId":          d.getString(5),
-			"MessageType":        "ua-data",
-			"PublisherId":        "Standalone_IIOTEdgeServer_opcpublisher",
-			"Messages":           msgList,
-		}
+// getTelemetryPayload serializes the telemetry values in the configured payload format.
+func (d *centralDevice) getTelemetryPayload(telemetry models.Telemetry) ([]byte, error) {
+	if d.config.Format == "opcua" {
+		return d.getOpcuaTelemetryPayload(telemetry)
+	}
+	return json.Marshal(telemetry)
+}
 
-		now := time.Now().UTC()
-		tvList := map[string]interface{}{
-			"plantName":          tm.PlantName,
-			"productionLine":     tm.ProductionLine,
-			"shiftNumber":        tm.ShiftNumber,
-			"batchNumber":        tm.BatchNumber,
-			"messageTimestamp":   tm.MessageTimestamp,
-			"totalPartsMade":     tm.TotalPartsMade,
-			"defectivePartsMade": tm.DefectivePartsMade,
-			"machineHealth":      tm.MachineHealth,
-			"oilLevel":           tm.OilLevel,
-			"temperature":        tm.Temperature,
-			"Kwh":                tm.Kwh,
-			"PlannedkWh":         tm.PlannedKwH,
-		}
+// getOpcuaTelemetryPayload wraps the telemetry values in an OPC UA publisher message.
+func (d *centralDevice) getOpcuaTelemetryPayload(tvList models.Telemetry) ([]byte, error) {
+	// OPCUA device sending JSON payload
+	msgGuid := d.getUUID()
+	payload := make(map[string]interface{})
+	msgList := make([]map[string]interface{}, 1)
+	d.telemetrySequenceNumber++
+	msgList[0] = map[string]interface{}{
+		"DataSetWriterId": fmt.Sprintf("%s-%s", d.deviceID, msgGuid),
+		"MetaDataVersion": map[string]interface{}{
+			"MajorVersion": 1,
+			"MinorVersion": 0,
+		},
+		"SequenceNumber": d.telemetrySequenceNumber, //  rand.Intn(100000),
+		"Status":         nil,
+		"Timestamp":      d.getDateTime(),
+		"Payload":        payload,
+	}
 
-		for name, value := range tvList {
-			opcuaNodeId := fmt.Sprintf("nsu=%s;s=%s", d.getString(20), d.getString(20))
-			payload[opcuaNodeId] = map[string]interface{}{
-				"ServerTimestamp": now,
-				"SourceTimestamp": now,
-				"StatusCode":      nil,
-				"Name":            name,
-				"Value":           value,
-			}
-			telemetryValues[name] = value
-		}
+	eventId := d.getUUID()
+	telemetryValues := map[string]interface{}{
+		"DataSetClassId":     nil,
+		"DataSetWriterGroup": d.deviceID,
+		"EventId":            eventId,
+		"MessageId":          d.getString(5),
+		"MessageType":        "ua-data",
+		"PublisherId":        "Standalone_IIOTEdgeServer_opcpublisher",
+		"Messages":           msgList,
+	}
 
-		body, err := json.Marshal(telemetryValues)
-		if err != nil {
-			return nil, err
+	// the node IDs are drawn in the order of the field names, so that a seeded device sends the same IDs on every run
+	names := make([]string, 0, len(tvList))
+	for name := range tvList {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	now := time.Now().UTC()
+	for _, name := range names {
+		value := tvList[name]
+		opcuaNodeId := fmt.Sprintf("nsu=%s;s=%s", d.getString(20), d.getString(20))
+		payload[opcuaNodeId] = map[string]interface{}{
+			"ServerTimestamp": now,
+			"SourceTimestamp": now,
+			"StatusCode":      nil,
+			"Name":            name,
+			"Value":           value,
 		}
-		return body, err
+		telemetryValues[name] = value
+	}
 
-	} else {
-		body, err := json.Marshal(tm)
-		if err != nil {
-			return nil, err
-		}
-		return body, err
+	body, err := json.Marshal(telemetryValues)
+	if err != nil {
+		return nil, err
 	}
+	return body, err
 }
 
 // getDateTime gets current date time as a string.
@@ -788,13 +1066,21 @@ func (d *centralDevice) getString(length int) string {
 	var charSet string = "abcdefghijklmnopqrstuvwxyzACBDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	var val strings.Builder
 	for i := 0; i < length; i++ {
-		val.WriteString(string(charSet[rand.Intn(len(charSet))]))
+		val.WriteString(string(charSet[d.rand.Intn(len(charSet))]))
 	}
 
 	return val.String()
 }
 
-//getTime gets the current time as string.
+// getUUID gets a random UUID from the random source of the device.
+func (d *centralDevice) getUUID() string {
+	buf := make([]byte, 16)
+	_, _ = d.rand.Read(buf)
+	id, _ := uuid.FormatUUID(buf)
+	return id
+}
+
+// getTime gets the current time as string.
 func (d *centralDevice) getTime() string {
 	return time.Now().UTC().Format(time.RFC3339)
 }
@@ -0,0 +1,146 @@
+package simulating
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+// powerMachine reports a fixed power usage, so that the energy derived from it is known.
+type powerMachine struct {
+	power float64
+}
+
+func (m *powerMachine) ModelID() string { return "" }
+
+func (m *powerMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	return models.Telemetry{"PowerUsage": m.power}, nil
+}
+
+func (m *powerMachine) ApplyProperty(name string, value interface{}) bool { return false }
+
+func (m *powerMachine) Commands() []string { return nil }
+
+func (m *powerMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return nil, errUnknownCommand
+}
+
+func TestScaledPowerReachesDerivedEnergy(t *testing.T) {
+	meter, err := NewPlantMeter(&MeterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenario := &Scenario{
+		Events:  []ScenarioEvent{{Duration: time.Hour, Target: "*", Type: ScenarioScale, Field: "PowerUsage", Factor: 10}},
+		started: time.Now().Add(-time.Minute),
+	}
+	spec := &MachineSpec{
+		DeviceID: "Test-PowerMachine-1",
+		Config:   &MachineConfig{Format: "json"},
+		Rand:     NewDeviceRand(1, "Test-PowerMachine-1"),
+		Scenario: scenario,
+		Meter:    meter,
+		App:      &models.CentralApplication{},
+	}
+	device := NewDevice(context.Background(), spec, &powerMachine{power: 6})
+	device.telemetryFrequency = 60 * 60
+
+	payload, err := device.getTelemetryMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var telemetry map[string]interface{}
+	if err := json.Unmarshal(payload, &telemetry); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first step lasts the telemetry interval of an hour, so the scaled power of 60 kW used 60 kWh
+	if power := telemetry["PowerUsage"]; power != 60.0 {
+		t.Errorf("PowerUsage = %v, want 60", power)
+	}
+	if co2 := telemetry["co2grams"]; co2 != getEmissions(60, defaultCarbonIntensity) {
+		t.Errorf("co2grams = %v, want %v", co2, getEmissions(60, defaultCarbonIntensity))
+	}
+	if kwh := meter.total.take(); kwh != 60 {
+		t.Errorf("meter total = %v kWh, want 60", kwh)
+	}
+}
+
+func TestPlantScaleSkipsMeterSum(t *testing.T) {
+	for _, test := range []struct {
+		target string
+		want   float64
+	}{
+		{"Amsterdam", 6},
+		{"Amsterdam-PlantMeter-1", 60},
+	} {
+		meter, err := NewPlantMeter(&MeterConfig{BaseLoads: []BaseLoadConfig{{Name: "none"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		scenario := &Scenario{
+			Events:  []ScenarioEvent{{Duration: time.Hour, Target: test.target, Type: ScenarioScale, Field: "kwh", Factor: 10}},
+			started: time.Now().Add(-time.Minute),
+		}
+		spec := &MachineSpec{
+			DeviceID:  "Amsterdam-PlantMeter-1",
+			PlantName: "Amsterdam",
+			Config:    meter.MachineConfig(),
+			Rand:      NewDeviceRand(1, "Amsterdam-PlantMeter-1"),
+			Scenario:  scenario,
+			App:       &models.CentralApplication{},
+		}
+		device := NewDevice(context.Background(), spec, NewPlantMeterMachine(spec, meter))
+		// the machines of the plant already scaled the energy they added to the meter
+		meter.Add(6)
+
+		payload, err := device.getTelemetryMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var telemetry map[string]interface{}
+		if err := json.Unmarshal(payload, &telemetry); err != nil {
+			t.Fatal(err)
+		}
+		if kwh := telemetry["kwh"]; kwh != test.want {
+			t.Errorf("target %s: kwh = %v, want %v", test.target, kwh, test.want)
+		}
+	}
+}
+
+func TestCommandsDuringTelemetry(t *testing.T) {
+	spec := &MachineSpec{
+		DeviceID: "Test-BoltMachine-1",
+		Config:   &MachineConfig{Format: "json"},
+		Rand:     NewDeviceRand(1, "Test-BoltMachine-1"),
+		App:      &models.CentralApplication{},
+	}
+	machine, err := newBoltMachine(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	device := NewDevice(context.Background(), spec, machine)
+
+	// run with -race to check that the commands do not race with the telemetry pump
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if _, err := device.handleCommand("refillOil", nil); err != nil {
+				t.Error(err)
+			}
+			if _, err := device.handleCommand("changeTool", nil); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := device.getTelemetryMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
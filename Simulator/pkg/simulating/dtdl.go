@@ -0,0 +1,386 @@
+package simulating
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	// DTDLMachineType configures a machine kind that is simulated from a DTDL interface.
+	DTDLMachineType struct {
+		Name       string            `json:"name"`       // machine kind, used as the plant configuration block name
+		Model      string            `json:"model"`      // path of the DTDL interface file
+		ModelID    string            `json:"modelId"`    // interface to simulate if the file holds several, defaults to the first
+		Generators []GeneratorConfig `json:"generators"` // signal generators of the telemetry fields
+	}
+
+	// dtdlTypes is a DTDL @type, which is either a single type or a list of types.
+	dtdlTypes []string
+
+	dtdlInterface struct {
+		ID       string            `json:"@id"`
+		Type     dtdlTypes         `json:"@type"`
+		Contents []dtdlContent     `json:"contents"`
+		Schemas  []json.RawMessage `json:"-"`
+	}
+
+	dtdlContent struct {
+		Type     dtdlTypes       `json:"@type"`
+		Name     string          `json:"name"`
+		Schema   json.RawMessage `json:"schema"`
+		Writable bool            `json:"writable"`
+	}
+
+	dtdlSchema struct {
+		ID          string    `json:"@id"`
+		Type        dtdlTypes `json:"@type"`
+		ValueSchema string    `json:"valueSchema"`
+		EnumValues  []struct {
+			Name      string      `json:"name"`
+			EnumValue interface{} `json:"enumValue"`
+		} `json:"enumValues"`
+	}
+
+	// dtdlField is a telemetry field of a DTDL interface with a primitive or enum schema.
+	dtdlField struct {
+		name       string
+		schema     string        // primitive schema, or the value schema of an enum
+		enumValues []interface{} // values of an enum schema
+		generator  *GeneratorConfig
+	}
+
+	dtdlModel struct {
+		id         string
+		fields     []dtdlField
+		properties map[string]bool // property names, mapped to whether they are writable
+		commands   []string
+	}
+
+	dtdlMachine struct {
+		model      *dtdlModel
+		spec       *MachineSpec
+		generators map[string]signalGenerator
+		properties map[string]interface{} // last values of the writable properties
+	}
+)
+
+// RegisterDTDLMachine loads the DTDL interface of a machine type and registers it as a machine kind.
+func RegisterDTDLMachine(machineType *DTDLMachineType) error {
+	if machineType.Name == "" {
+		return fmt.Errorf("machine type of %s has no name", machineType.Model)
+	}
+	if registered, ok := registeredMachineKind(machineType.Name); ok {
+		return fmt.Errorf("machine type %s has the name of machine kind %s", machineType.Name, registered)
+	}
+	model, err := loadDTDLModel(machineType.Model, machineType.ModelID)
+	if err != nil {
+		return err
+	}
+
+	// attach the configured generators to the fields of the interface
+	for i := range machineType.Generators {
+		generator := &machineType.Generators[i]
+		found := false
+		for j := range model.fields {
+			if strings.EqualFold(model.fields[j].name, generator.Field) {
+				model.fields[j].generator = generator
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("machine type %s has a generator for unknown telemetry %s", machineType.Name, generator.Field)
+		}
+		if strings.EqualFold(generator.Type, GeneratorEnumCycle) && len(generator.Values) == 0 {
+			// cycle through the values of the enum schema unless other values are given
+			for _, field := range model.fields {
+				if strings.EqualFold(field.name, generator.Field) {
+					generator.Values = field.enumValues
+				}
+			}
+		}
+		// validate the generator, the devices create their own
+		if _, err := newSignalGenerator(generator, nil); err != nil {
+			return err
+		}
+	}
+
+	RegisterMachine(machineType.Name, func(spec *MachineSpec) (Machine, error) {
+		return newDTDLMachine(model, spec)
+	})
+	return nil
+}
+
+// loadDTDLModel reads the telemetry, properties and commands of an interface in a DTDL file.
+func loadDTDLModel(path string, modelID string) (*dtdlModel, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// a DTDL file holds either a single interface or a list of interfaces
+	var raw []json.RawMessage
+	if err = json.Unmarshal(body, &raw); err != nil {
+		raw = []json.RawMessage{body}
+	}
+
+	var iface *dtdlInterface
+	for _, r := range raw {
+		var candidate dtdlInterface
+		if err = json.Unmarshal(r, &candidate); err != nil {
+			return nil, fmt.Errorf("failed to parse DTDL interface in %s. %w", path, err)
+		}
+		if modelID == "" || candidate.ID == modelID {
+			candidate.Schemas = getDTDLSchemas(r)
+			iface = &candidate
+			break
+		}
+	}
+	if iface == nil {
+		return nil, fmt.Errorf("interface %s not found in %s", modelID, path)
+	}
+
+	model := &dtdlModel{
+		id:         iface.ID,
+		properties: map[string]bool{},
+	}
+	for _, content := range iface.Contents {
+		switch {
+		case content.Type.has("Telemetry"):
+			field, err := getDTDLField(content, iface.Schemas)
+			if err != nil {
+				log.Warn().Err(err).Str("model", iface.ID).Str("telemetry", content.Name).Msg("skipping telemetry")
+				continue
+			}
+			model.fields = append(model.fields, *field)
+		case content.Type.has("Property"):
+			model.properties[content.Name] = content.Writable
+		case content.Type.has("Command"):
+			model.commands = append(model.commands, content.Name)
+		}
+	}
+	return model, nil
+}
+
+// getDTDLSchemas gets the schemas defined in the schemas section of an interface, which is an object or a list.
+func getDTDLSchemas(iface json.RawMessage) []json.RawMessage {
+	var wrapper struct {
+		Schemas json.RawMessage `json:"schemas"`
+	}
+	if err := json.Unmarshal(iface, &wrapper); err != nil || len(wrapper.Schemas) == 0 {
+		return nil
+	}
+	var schemas []json.RawMessage
+	if err := json.Unmarshal(wrapper.Schemas, &schemas); err != nil {
+		schemas = []json.RawMessage{wrapper.Schemas}
+	}
+	return schemas
+}
+
+// getDTDLField gets the telemetry field for a content with a primitive or enum schema.
+func getDTDLField(content dtdlContent, schemas []json.RawMessage) (*dtdlField, error) {
+	var name string
+	if err := json.Unmarshal(content.Schema, &name); err == nil {
+		if isDTDLPrimitive(name) {
+			return &dtdlField{name: content.Name, schema: name}, nil
+		}
+		// the schema refers to one defined in the schemas section of the interface
+		for _, s := range schemas {
+			var schema dtdlSchema
+			if json.Unmarshal(s, &schema) == nil && schema.ID == name {
+				return getDTDLEnumField(content.Name, &schema)
+			}
+		}
+		return nil, fmt.Errorf("unknown schema %s", name)
+	}
+
+	var schema dtdlSchema
+	if err := json.Unmarshal(content.Schema, &schema); err != nil {
+		return nil, err
+	}
+	return getDTDLEnumField(content.Name, &schema)
+}
+
+func getDTDLEnumField(name string, schema *dtdlSchema) (*dtdlField, error) {
+	if !schema.Type.has("Enum") {
+		return nil, fmt.Errorf("unsupported schema %v", schema.Type)
+	}
+	field := &dtdlField{name: name, schema: schema.ValueSchema}
+	for _, value := range schema.EnumValues {
+		field.enumValues = append(field.enumValues, value.EnumValue)
+	}
+	return field, nil
+}
+
+func isDTDLPrimitive(schema string) bool {
+	switch schema {
+	case "boolean", "date", "dateTime", "double", "duration", "float", "integer", "long", "string", "time":
+		return true
+	}
+	return false
+}
+
+func (t *dtdlTypes) UnmarshalJSON(body []byte) error {
+	var single string
+	if err := json.Unmarshal(body, &single); err == nil {
+		*t = dtdlTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(body, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+func (t dtdlTypes) has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func newDTDLMachine(model *dtdlModel, spec *MachineSpec) (Machine, error) {
+	m := &dtdlMachine{
+		model:      model,
+		spec:       spec,
+		generators: map[string]signalGenerator{},
+		properties: map[string]interface{}{},
+	}
+
+	// every device gets its own generators so that their state is not shared
+	for _, field := range model.fields {
+		cfg := field.generator
+		if cfg == nil {
+			cfg = m.getDefaultGenerator(&field)
+		}
+		if cfg == nil {
+			continue
+		}
+		generator, err := newSignalGenerator(cfg, spec.Rand)
+		if err != nil {
+			return nil, err
+		}
+		m.generators[field.name] = generator
+	}
+	return m, nil
+}
+
+// getDefaultGenerator gets the generator of a field without configuration, based on its schema.
+func (m *dtdlMachine) getDefaultGenerator(field *dtdlField) *GeneratorConfig {
+	if len(field.enumValues) > 0 {
+		return &GeneratorConfig{Field: field.name, Type: GeneratorEnumCycle, Values: field.enumValues, Period: 10 * time.Minute}
+	}
+	switch field.schema {
+	case "double", "float":
+		return &GeneratorConfig{Field: field.name, Type: GeneratorRandomWalk, Start: 50, Min: 0, Max: 100, Step: 1}
+	case "integer", "long":
+		return &GeneratorConfig{Field: field.name, Type: GeneratorCounter, Start: 0, Step: 1}
+	case "boolean":
+		return &GeneratorConfig{Field: field.name, Type: GeneratorConstant, Value: true}
+	case "string":
+		value := ""
+		switch strings.ToLower(field.name) {
+		case "plantname":
+			value = m.spec.PlantName
+		case "productionline":
+			value = m.spec.ProductionLine
+		case "deviceid":
+			value = m.spec.DeviceID
+		}
+		return &GeneratorConfig{Field: field.name, Type: GeneratorConstant, Value: value}
+	}
+	return nil
+}
+
+func (m *dtdlMachine) ModelID() string {
+	return m.model.id
+}
+
+// NextTelemetry gets the next value of every telemetry field of the interface, converted to its schema.
+func (m *dtdlMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	telemetry := models.Telemetry{}
+	for _, field := range m.model.fields {
+		switch field.schema {
+		case "dateTime":
+			telemetry[field.name] = tick.Now
+			continue
+		case "date":
+			telemetry[field.name] = tick.Now.Format("2006-01-02")
+			continue
+		case "time":
+			telemetry[field.name] = tick.Now.Format("15:04:05")
+			continue
+		}
+
+		generator, ok := m.generators[field.name]
+		if !ok {
+			continue
+		}
+		value, err := convertDTDLValue(field.schema, generator.next(tick.Now))
+		if err != nil {
+			return nil, fmt.Errorf("telemetry %s: %w", field.name, err)
+		}
+		telemetry[field.name] = value
+	}
+	return telemetry, nil
+}
+
+// convertDTDLValue converts a generated value to the given DTDL primitive schema.
+func convertDTDLValue(schema string, value interface{}) (interface{}, error) {
+	switch schema {
+	case "integer", "long":
+		if f, ok := getFloatValue(value); ok {
+			return int(math.Round(f)), nil
+		}
+	case "double", "float":
+		if f, ok := getFloatValue(value); ok {
+			return math.Round(f*100) / 100, nil
+		}
+	case "boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		if f, ok := getFloatValue(value); ok {
+			return f >= 0.5, nil
+		}
+	case "string", "duration":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprintf("%v", value), nil
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, schema)
+}
+
+// ApplyProperty stores the value of any writable property of the interface.
+func (m *dtdlMachine) ApplyProperty(name string, value interface{}) bool {
+	if !m.model.properties[name] {
+		return false
+	}
+	m.properties[name] = value
+	return true
+}
+
+func (m *dtdlMachine) Commands() []string {
+	return m.model.commands
+}
+
+// HandleCommand acknowledges any command of the interface.
+func (m *dtdlMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	for _, command := range m.model.commands {
+		if command == name {
+			return map[string]interface{}{}, nil
+		}
+	}
+	return nil, errUnknownCommand
+}
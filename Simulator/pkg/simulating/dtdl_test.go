@@ -0,0 +1,11 @@
+package simulating
+
+import "testing"
+
+func TestDTDLMachineTypeOfBuiltInKind(t *testing.T) {
+	for _, name := range []string{"boltMachine", "BoltMachine"} {
+		if err := RegisterDTDLMachine(&DTDLMachineType{Name: name, Model: "bolt.json"}); err == nil {
+			t.Errorf("RegisterDTDLMachine(%s) = nil, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package simulating
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// energyDefaults holds the energy model of every machine kind that has one, which a partial energy block of the kind
+// is merged over.
+var energyDefaults = map[string]EnergyConfig{}
+
+// EnergyConfig configures the energy model of a machine kind.
+type EnergyConfig struct {
+	BasePower          float64 `json:"basePower"`          // power drawn while running, regardless of the output, in kilowatt
+	StandbyPower       float64 `json:"standbyPower"`       // power drawn while stopped or switched off, in kilowatt
+	EnergyPerPart      float64 `json:"energyPerPart"`      // energy used to make one part in kWh
+	HeaterPower        float64 `json:"heaterPower"`        // heater power per degree above the ambient temperature, in kilowatt
+	AmbientTemperature float64 `json:"ambientTemperature"` // temperature the heaters do not need to keep up, the temperature around the machine if zero
+	WarningOverhead    float64 `json:"warningOverhead"`    // extra energy used while running in Warning health, e.g. 0.15 for 15%
+}
+
+// defaultEnergyConfig gets the energy model of the given machine kind, or nil if the kind has none.
+func defaultEnergyConfig(kind string) *EnergyConfig {
+	for name, defaults := range energyDefaults {
+		if strings.EqualFold(name, kind) {
+			cfg := defaults
+			return &cfg
+		}
+	}
+	return nil
+}
+
+// getEnergyUsage gets the energy in kWh used over a step, given the running time, stopped time, parts made,
+// temperature and health of the machine.
+func (e *EnergyConfig) getEnergyUsage(runTime time.Duration, stopTime time.Duration, parts int, temperature float64, health string) float64 {
+	running := (e.BasePower+e.getHeaterPower(temperature))*runTime.Hours() + e.EnergyPerPart*float64(parts)
+	if health == "Warning" {
+		running *= 1 + e.WarningOverhead
+	}
+	return running + e.StandbyPower*stopTime.Hours()
+}
+
+// getHeaterPower gets the heater power needed to hold the given temperature.
+func (e *EnergyConfig) getHeaterPower(temperature float64) float64 {
+	return e.HeaterPower * math.Max(0, temperature-e.AmbientTemperature)
+}
+
+// getStandbyPower gets the standby power configured for a machine kind, which may be zero for a machine that is off at
+// the mains, or the given default without an energy configuration.
+func getStandbyPower(cfg *MachineConfig, defaultPower float64) float64 {
+	if cfg.Energy != nil {
+		return cfg.Energy.StandbyPower
+	}
+	return defaultPower
+}
@@ -0,0 +1,146 @@
+package simulating
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+const (
+	fanningPhaseRoasting = "Roasting" // burner on, beans are roasted with a moderate air flow
+	fanningPhaseCooling  = "Cooling"  // burner off, fan at full speed to cool the batch down
+
+	fanningChargeTemperature  = 160.0 // drum temperature when a new batch is charged
+	fanningDropTemperature    = 225.0 // drum temperature at the end of a roast
+	fanningRoastFanSpeed      = 1100.0
+	fanningCoolFanSpeed       = 1800.0
+	fanningStandbyPower       = 1.5  // controls and drum drive of a stopped machine in kilowatt
+	fanningStandbyTemperature = 40.0 // drum temperature a stopped machine cools down to
+)
+
+type fanningMachine struct {
+	modelID      string                 // device model ID of fanning machines.
+	rand         *rand.Rand             // random source of the device
+	standbyPower float64                // power drawn while stopped or switched off in kilowatt
+	machine      *models.FanningMachine // fanning machine state
+}
+
+func init() {
+	RegisterMachine("fanningMachine", newFanningMachine)
+	energyDefaults["fanningMachine"] = EnergyConfig{StandbyPower: fanningStandbyPower}
+}
+
+func newFanningMachine(spec *MachineSpec) (Machine, error) {
+	return &fanningMachine{
+		modelID:      spec.App.FanningMachineModelID,
+		standbyPower: getStandbyPower(spec.Config, fanningStandbyPower),
+		rand:         spec.Rand,
+		machine: &models.FanningMachine{
+			PlantName:            spec.PlantName,
+			ProductionLine:       spec.ProductionLine,
+			RoastDurationMinutes: 14,
+			CoolDurationMinutes:  4,
+			ChasisTemperature:    160,
+			FanSpeed:             1100,
+			PowerUsage:           18,
+			Format:               spec.Config.Format,
+		},
+	}, nil
+}
+
+func (m *fanningMachine) ModelID() string {
+	return m.modelID
+}
+
+// NextTelemetry advances the roast cycle of the fanning machine and builds its telemetry message.
+func (m *fanningMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	now := tick.Now
+	roastDuration := time.Duration(m.machine.RoastDurationMinutes) * time.Minute
+	coolDuration := time.Duration(m.machine.CoolDurationMinutes) * time.Minute
+
+	// move through the phases that completed since the previous message
+	if m.machine.PhaseStarted.IsZero() {
+		m.machine.Phase = fanningPhaseRoasting
+		m.machine.PhaseStarted = now
+	} else {
+		// the roast cycle is paused while the machine is stopped
+		m.machine.PhaseStarted = m.machine.PhaseStarted.Add(tick.Interval - tick.RunTime)
+	}
+	for {
+		if m.machine.Phase == fanningPhaseRoasting && now.Sub(m.machine.PhaseStarted) >= roastDuration {
+			m.machine.Phase = fanningPhaseCooling
+			m.machine.PhaseStarted = m.machine.PhaseStarted.Add(roastDuration)
+		} else if m.machine.Phase == fanningPhaseCooling && now.Sub(m.machine.PhaseStarted) >= coolDuration {
+			m.machine.Phase = fanningPhaseRoasting
+			m.machine.PhaseStarted = m.machine.PhaseStarted.Add(coolDuration)
+		} else {
+			break
+		}
+	}
+
+	elapsed := now.Sub(m.machine.PhaseStarted)
+	roastingTime := m.machine.RoastDurationMinutes
+	if tick.State != StateRunning {
+		// burner and fan are off, the drum slowly cools down
+		standbyTemperature := fanningStandbyTemperature + tick.Ambient - referenceTemperature
+		m.machine.ChasisTemperature += (standbyTemperature - m.machine.ChasisTemperature) * 0.1
+		m.machine.FanSpeed = 0
+		m.machine.PowerUsage = m.standbyPower
+	} else if m.machine.Phase == fanningPhaseRoasting {
+		// the drum temperature dips when the batch is charged and then climbs towards the drop temperature
+		progress := elapsed.Minutes() / roastDuration.Minutes()
+		m.machine.ChasisTemperature = fanningDropTemperature - (fanningDropTemperature-fanningChargeTemperature)*math.Pow(1-progress, 2)
+		m.machine.FanSpeed = fanningRoastFanSpeed
+		// burner power is highest right after charging to recover the temperature
+		m.machine.PowerUsage = 18 + 10*(1-progress)
+		roastingTime = int(elapsed.Minutes())
+	} else {
+		// the batch cools down exponentially while the drum is held at charge temperature
+		progress := elapsed.Minutes() / coolDuration.Minutes()
+		m.machine.ChasisTemperature = fanningChargeTemperature + (fanningDropTemperature-fanningChargeTemperature)*math.Exp(-3*progress)
+		m.machine.FanSpeed = fanningCoolFanSpeed
+		m.machine.PowerUsage = 6.5
+	}
+	m.machine.ChasisTemperature += m.rand.Float64()*2 - 1
+	if m.machine.FanSpeed > 0 {
+		m.machine.FanSpeed += m.rand.Float64()*40 - 20
+	}
+	if tick.State == StateRunning {
+		m.machine.PowerUsage = getLoadPower(m.machine.PowerUsage, m.standbyPower, tick.Load)
+	}
+	m.machine.PowerUsage += m.rand.Float64()*0.6 - 0.3
+
+	telemetry := models.FanningMachineTelemetryMessage{
+		DeviceType:        "FanningSensor",
+		PlantName:         m.machine.PlantName,
+		ProductionLine:    m.machine.ProductionLine,
+		MessageTimestamp:  now,
+		ChasisTemperature: math.Round(m.machine.ChasisTemperature*10) / 10,
+		Force:             math.Round(m.machine.FanSpeed),
+		RoastingTime:      roastingTime,
+		PowerUsage:        math.Round(m.machine.PowerUsage*100) / 100,
+	}
+
+	return toTelemetry(telemetry)
+}
+
+func (m *fanningMachine) ApplyProperty(name string, value interface{}) bool {
+	switch name {
+	case "roastDurationMinutes":
+		if val, ok := getFloatValue(value); ok && val > 0 {
+			m.machine.RoastDurationMinutes = int(val)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *fanningMachine) Commands() []string {
+	return nil
+}
+
+func (m *fanningMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return nil, errUnknownCommand
+}
@@ -0,0 +1,232 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+const (
+	FloorSensorKind = "floorSensor" // kind of the floor sensor devices, used in their device IDs
+
+	floorStep = time.Minute // longest step of the floor temperature integration
+)
+
+type (
+	// FloorConfig configures a factory floor and the sensor device that measures its climate.
+	FloorConfig struct {
+		Name            string               `json:"name"`            // name of the floor, e.g. Hall 1
+		Format          string               `json:"format"`          // telemetry payload format, json or opcua
+		Machines        []FloorMachineConfig `json:"machines"`        // machines on the floor, all machines of the plant if empty
+		HeatShare       float64              `json:"heatShare"`       // share of the machine energy that heats the floor, defaults to 0.8
+		ThermalMass     float64              `json:"thermalMass"`     // energy that warms the floor by one degree in kWh, defaults to 50
+		Conductance     float64              `json:"conductance"`     // heat lost to outside per degree of difference in kilowatt, defaults to 4
+		HeatingSetpoint float64              `json:"heatingSetpoint"` // temperature the heating keeps the floor at, defaults to 16
+		HeatingPower    float64              `json:"heatingPower"`    // capacity of the heating in kilowatt, defaults to 100
+	}
+
+	// FloorMachineConfig places a machine on a floor.
+	FloorMachineConfig struct {
+		Machine string `json:"machine"` // machine kind, e.g. boltMachine
+		Index   int    `json:"index"`   // 1-based index of the machine within the plant, defaults to 1
+	}
+
+	// OutdoorConfig configures the weather at a plant, from an hourly weather file or else as a daily temperature cycle.
+	OutdoorConfig struct {
+		File     string   `json:"file"`     // CSV file of hourly temperatures and humidities, by timestamp or hour of the day
+		Mean     *float64 `json:"mean"`     // mean outdoor temperature of the hours the file does not cover, defaults to 10
+		Swing    *float64 `json:"swing"`    // difference between the mean and the warmest time of day at 15:00, defaults to 5
+		Humidity *float64 `json:"humidity"` // relative humidity in percent of the hours the file does not cover, defaults to 70
+	}
+
+	// Floor holds the climate of a factory floor, heated by the machines that add their energy to it.
+	Floor struct {
+		cfg      *FloorConfig
+		weather  *Weather
+		total    energyTotal // energy the machines on the floor used since the previous read
+		climate  floorClimate
+		machines map[string]bool
+	}
+
+	// floorClimate holds the temperature the floor sensor last measured, for the machines on the floor to read.
+	floorClimate struct {
+		mutex       sync.Mutex
+		temperature float64
+		measured    bool
+	}
+
+	floorSensorMachine struct {
+		modelID     string     // device model ID of floor sensors.
+		plantName   string     // plant the floor belongs to.
+		floorID     string     // ID of the floor twin, the device ID.
+		floor       *Floor     // floor the sensor measures.
+		rand        *rand.Rand // random source of the device
+		temperature float64    // floor temperature
+		started     bool
+	}
+)
+
+// NewFloor creates a floor of a plant in the weather of the plant, filling in the defaults of its configuration.
+func NewFloor(cfg *FloorConfig, weather *Weather) *Floor {
+	if cfg.HeatShare <= 0 {
+		cfg.HeatShare = 0.8
+	}
+	if cfg.ThermalMass <= 0 {
+		cfg.ThermalMass = 50
+	}
+	if cfg.Conductance <= 0 {
+		cfg.Conductance = 4
+	}
+	if cfg.HeatingSetpoint == 0 {
+		cfg.HeatingSetpoint = 16
+	}
+	if cfg.HeatingPower == 0 {
+		cfg.HeatingPower = 100
+	}
+	floor := &Floor{cfg: cfg, weather: weather}
+	if len(cfg.Machines) > 0 {
+		floor.machines = map[string]bool{}
+		for _, machine := range cfg.Machines {
+			if machine.Index == 0 {
+				machine.Index = 1
+			}
+			floor.machines[machineKey(machine.Machine, machine.Index)] = true
+		}
+	}
+	return floor
+}
+
+// Name gets the name of the floor.
+func (f *Floor) Name() string {
+	return f.cfg.Name
+}
+
+// Contains tells whether the n-th machine of a kind is on the floor.
+func (f *Floor) Contains(kind string, index int) bool {
+	return f.machines == nil || f.machines[machineKey(kind, index)]
+}
+
+// MachineConfig gets the configuration of the floor sensor device.
+func (f *Floor) MachineConfig() *MachineConfig {
+	return sensorConfig(f.cfg.Format)
+}
+
+// AddHeat adds the energy a machine on the floor used over a step.
+func (f *Floor) AddHeat(kwh float64) {
+	if f == nil {
+		return
+	}
+	f.total.add(kwh)
+}
+
+// Temperature gets the temperature of the floor at the given local time, as last measured by its sensor. Before the
+// first measurement it is the heating setpoint or the outdoor temperature, whichever is warmer.
+func (f *Floor) Temperature(at time.Time) float64 {
+	f.climate.mutex.Lock()
+	defer f.climate.mutex.Unlock()
+	if !f.climate.measured {
+		return math.Max(f.cfg.HeatingSetpoint, f.weather.Temperature(at))
+	}
+	return f.climate.temperature
+}
+
+func (f *Floor) setTemperature(temperature float64) {
+	f.climate.mutex.Lock()
+	defer f.climate.mutex.Unlock()
+	f.climate.temperature = temperature
+	f.climate.measured = true
+}
+
+// machineKey gets the key of the n-th machine of a kind in a plant.
+func machineKey(kind string, index int) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(kind), index)
+}
+
+// NewFloorSensorMachine creates the sensor device of a floor, which reports its temperature and comfort index.
+func NewFloorSensorMachine(spec *MachineSpec, floor *Floor) Machine {
+	if spec.Rand == nil {
+		spec.Rand = NewDeviceRand(time.Now().UnixNano(), spec.DeviceID)
+	}
+	return &floorSensorMachine{
+		modelID:   spec.App.FloorSensorModelID,
+		plantName: spec.PlantName,
+		floorID:   spec.DeviceID,
+		floor:     floor,
+		rand:      spec.Rand,
+	}
+}
+
+func (m *floorSensorMachine) ModelID() string {
+	return m.modelID
+}
+
+// NextTelemetry warms the floor with the heat of its machines and the heating, and cools it down to the outdoor
+// temperature.
+func (m *floorSensorMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	cfg := m.floor.cfg
+	start := tick.Local.Add(-tick.Interval)
+	if !m.started {
+		// the floor starts at the heating setpoint or the outdoor temperature, whichever is warmer
+		m.temperature = m.floor.Temperature(start)
+		m.started = true
+	}
+
+	// the machine heat is spread evenly over the step
+	machineHeat := 0.0
+	if tick.Interval > 0 {
+		machineHeat = m.floor.total.take() * cfg.HeatShare / tick.Interval.Hours()
+	}
+	heating := 0.0
+	for t := time.Duration(0); t < tick.Interval; t += floorStep {
+		step := floorStep
+		if tick.Interval-t < step {
+			step = tick.Interval - t
+		}
+		outdoor := m.floor.weather.Temperature(start.Add(t))
+		// the heating runs at full power two degrees below its setpoint
+		heating = math.Min(cfg.HeatingPower, math.Max(0, cfg.HeatingPower*(cfg.HeatingSetpoint-m.temperature)/2))
+		power := machineHeat + heating - cfg.Conductance*(m.temperature-outdoor)
+		m.temperature += power * step.Hours() / cfg.ThermalMass
+	}
+	m.floor.setTemperature(m.temperature)
+	temperature := m.temperature + m.rand.Float64()*0.2 - 0.1
+
+	telemetry := models.FloorSensorTelemetryMessage{
+		DeviceType:         "FloorSensor",
+		PlantName:          m.plantName,
+		MessageTimestamp:   tick.Now,
+		FloorId:            m.floorID,
+		FloorName:          cfg.Name,
+		Temperature:        math.Round(temperature*10) / 10,
+		ComfortIndex:       getComfortIndex(temperature),
+		OutdoorTemperature: math.Round(m.floor.weather.Temperature(tick.Local)*10) / 10,
+		OutdoorHumidity:    math.Round(m.floor.weather.Humidity(tick.Local)),
+		MachineHeat:        math.Round(machineHeat*100) / 100,
+		HeatingPower:       math.Round(heating*100) / 100,
+	}
+	return toTelemetry(telemetry)
+}
+
+func (m *floorSensorMachine) ApplyProperty(name string, value interface{}) bool {
+	return false
+}
+
+func (m *floorSensorMachine) Commands() []string {
+	return nil
+}
+
+func (m *floorSensorMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return nil, errUnknownCommand
+}
+
+// getComfortIndex gets the thermal comfort of working on the floor from 0 to 100. It is 100 between 18 and 24 degrees
+// and drops by 12.5 for every degree outside that band.
+func getComfortIndex(temperature float64) float64 {
+	distance := math.Max(0, math.Max(18-temperature, temperature-24))
+	return math.Round(math.Max(0, 100-12.5*distance)*10) / 10
+}
@@ -0,0 +1,159 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	GeneratorRandomWalk = "randomWalk" // bounded random walk, like the bolt machine temperature
+	GeneratorSine       = "sine"       // sine wave between min and max
+	GeneratorStep       = "step"       // step function through a list of levels
+	GeneratorEnumCycle  = "enumCycle"  // cycles through a list of (enum) values
+	GeneratorCounter    = "counter"    // monotonic counter, optionally wrapping at max
+	GeneratorConstant   = "constant"   // constant value with optional noise
+)
+
+type (
+	// GeneratorConfig configures the signal generator of a telemetry field.
+	GeneratorConfig struct {
+		Field  string        `json:"field"`  // name of the telemetry field in the DTDL interface
+		Type   string        `json:"type"`   // randomWalk, sine, step, enumCycle, counter or constant
+		Start  float64       `json:"start"`  // initial value of a random walk or counter
+		Min    float64       `json:"min"`    // lower bound of a random walk or sine
+		Max    float64       `json:"max"`    // upper bound of a random walk or sine, wrap value of a counter
+		Step   float64       `json:"step"`   // random walk step size or counter increment
+		Period time.Duration `json:"period"` // sine period, or how long each step level or enum value lasts
+		Values []interface{} `json:"values"` // step levels or enum values
+		Value  interface{}   `json:"value"`  // constant value
+		Noise  float64       `json:"noise"`  // amplitude of the uniform noise added to the value
+	}
+
+	// signalGenerator produces the next value of a telemetry signal.
+	signalGenerator interface {
+		next(now time.Time) interface{}
+	}
+
+	randomWalkGenerator struct {
+		value, min, max, step float64
+		rand                  *rand.Rand
+	}
+
+	sineGenerator struct {
+		min, max, noise float64
+		period          time.Duration
+		rand            *rand.Rand
+	}
+
+	sequenceGenerator struct {
+		values []interface{}
+		period time.Duration
+		noise  float64
+		rand   *rand.Rand
+	}
+
+	counterGenerator struct {
+		value, step, max float64
+	}
+
+	constantGenerator struct {
+		value interface{}
+		noise float64
+		rand  *rand.Rand
+	}
+)
+
+// newSignalGenerator creates the signal generator described by the configuration, drawing from the given random source.
+func newSignalGenerator(cfg *GeneratorConfig, rand *rand.Rand) (signalGenerator, error) {
+	switch strings.ToLower(cfg.Type) {
+	case strings.ToLower(GeneratorRandomWalk):
+		if cfg.Max < cfg.Min {
+			return nil, fmt.Errorf("random walk of %s has max below min", cfg.Field)
+		}
+		step := cfg.Step
+		if step == 0 {
+			step = (cfg.Max - cfg.Min) / 100
+		}
+		return &randomWalkGenerator{value: cfg.Start, min: cfg.Min, max: cfg.Max, step: step, rand: rand}, nil
+	case strings.ToLower(GeneratorSine):
+		if cfg.Period <= 0 {
+			return nil, fmt.Errorf("sine of %s needs a period", cfg.Field)
+		}
+		return &sineGenerator{min: cfg.Min, max: cfg.Max, noise: cfg.Noise, period: cfg.Period, rand: rand}, nil
+	case strings.ToLower(GeneratorStep), strings.ToLower(GeneratorEnumCycle):
+		if len(cfg.Values) == 0 {
+			return nil, fmt.Errorf("%s of %s needs values", cfg.Type, cfg.Field)
+		}
+		if cfg.Period <= 0 {
+			return nil, fmt.Errorf("%s of %s needs a period", cfg.Type, cfg.Field)
+		}
+		return &sequenceGenerator{values: cfg.Values, period: cfg.Period, noise: cfg.Noise, rand: rand}, nil
+	case strings.ToLower(GeneratorCounter):
+		step := cfg.Step
+		if step == 0 {
+			step = 1
+		}
+		return &counterGenerator{value: cfg.Start, step: step, max: cfg.Max}, nil
+	case strings.ToLower(GeneratorConstant):
+		return &constantGenerator{value: cfg.Value, noise: cfg.Noise, rand: rand}, nil
+	default:
+		return nil, fmt.Errorf("unknown generator type %s for %s", cfg.Type, cfg.Field)
+	}
+}
+
+func (g *randomWalkGenerator) next(now time.Time) interface{} {
+	if g.value >= g.max {
+		g.value -= g.step
+	} else if g.value <= g.min {
+		g.value += g.step
+	} else {
+		if g.rand.Intn(100) > 50 {
+			g.value += g.step
+		} else {
+			g.value -= g.step
+		}
+	}
+	return g.value
+}
+
+func (g *sineGenerator) next(now time.Time) interface{} {
+	phase := 2 * math.Pi * float64(now.UnixNano()%int64(g.period)) / float64(g.period)
+	value := g.min + (g.max-g.min)*(1+math.Sin(phase))/2
+	return value + addNoise(g.rand, g.noise)
+}
+
+func (g *sequenceGenerator) next(now time.Time) interface{} {
+	index := int((now.UnixNano() / int64(g.period)) % int64(len(g.values)))
+	value := g.values[index]
+	if f, ok := getFloatValue(value); ok && g.noise > 0 {
+		return f + addNoise(g.rand, g.noise)
+	}
+	return value
+}
+
+func (g *counterGenerator) next(now time.Time) interface{} {
+	value := g.value
+	g.value += g.step
+	if g.max > 0 && g.value > g.max {
+		g.value = 0
+	}
+	return value
+}
+
+func (g *constantGenerator) next(now time.Time) interface{} {
+	if f, ok := getFloatValue(g.value); ok && g.noise > 0 {
+		return f + addNoise(g.rand, g.noise)
+	}
+	return g.value
+}
+
+// addNoise gets uniform noise between -amplitude and amplitude.
+func addNoise(rand *rand.Rand, amplitude float64) float64 {
+	if amplitude <= 0 {
+		return 0
+	}
+	return rand.Float64()*2*amplitude - amplitude
+}
@@ -0,0 +1,129 @@
+package simulating
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+const (
+	grindingBaseForce     = 120.0 // grinding force of a new wheel in pound
+	grindingBaseVibration = 45.0  // vibration frequency of a new wheel in hertz
+	grindingBasePower     = 15.0  // motor power of a new wheel in kilowatt
+
+	grindingStandbyPower       = 1.2  // coolant pump and controls of a stopped machine in kilowatt
+	grindingStandbyTemperature = 25.0 // chassis temperature a stopped machine cools down to
+)
+
+type grindingMachine struct {
+	modelID      string                  // device model ID of grinding machines.
+	rand         *rand.Rand              // random source of the device
+	standbyPower float64                 // power drawn while stopped or switched off in kilowatt
+	machine      *models.GrindingMachine // grinding machine state
+}
+
+func init() {
+	RegisterMachine("grindingMachine", newGrindingMachine)
+	energyDefaults["grindingMachine"] = EnergyConfig{StandbyPower: grindingStandbyPower}
+}
+
+func newGrindingMachine(spec *MachineSpec) (Machine, error) {
+	return &grindingMachine{
+		modelID:      spec.App.GrindingMachineModelID,
+		standbyPower: getStandbyPower(spec.Config, grindingStandbyPower),
+		rand:         spec.Rand,
+		machine: &models.GrindingMachine{
+			PlantName:            spec.PlantName,
+			ProductionLine:       spec.ProductionLine,
+			GrindDurationMinutes: 30,
+			WheelLifeHours:       72,
+			WheelWear:            0,
+			ChasisTemperature:    35,
+			Format:               spec.Config.Format,
+		},
+	}, nil
+}
+
+func (m *grindingMachine) ModelID() string {
+	return m.modelID
+}
+
+// NextTelemetry wears the grinding wheel down and builds the telemetry message of the grinding machine.
+func (m *grindingMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	now := tick.Now
+	if m.machine.LastUpdate.IsZero() {
+		m.machine.BatchStarted = now
+	}
+
+	// the wheel wears linearly with grinding time and is replaced at the end of its life
+	m.machine.LastUpdate = now
+	m.machine.WheelWear += tick.RunTime.Hours() / m.machine.WheelLifeHours
+	if m.machine.WheelWear >= 1.0 {
+		m.machine.WheelWear = 0.0
+	}
+
+	// batches are paused while the machine is stopped
+	m.machine.BatchStarted = m.machine.BatchStarted.Add(tick.Interval - tick.RunTime)
+	grindDuration := time.Duration(m.machine.GrindDurationMinutes) * time.Minute
+	for now.Sub(m.machine.BatchStarted) >= grindDuration {
+		m.machine.BatchStarted = m.machine.BatchStarted.Add(grindDuration)
+	}
+
+	// a worn wheel needs more force, vibrates more and heats up the chassis
+	wear := m.machine.WheelWear
+	force := grindingBaseForce + 80*wear + m.rand.Float64()*6 - 3
+	vibration := grindingBaseVibration + 30*wear*wear + m.rand.Float64()*2 - 1
+	powerUsage := getLoadPower(grindingBasePower+6*wear, m.standbyPower, tick.Load) + m.rand.Float64()*0.8 - 0.4
+
+	// the chassis runs warmer in a warm hall
+	target := 35 + 15*wear + (tick.Ambient-referenceTemperature)*0.5
+	if tick.State != StateRunning {
+		// the wheel is not in contact, only the coolant pump and controls draw power
+		force = 0
+		vibration = m.rand.Float64() * 2
+		powerUsage = m.standbyPower + m.rand.Float64()*0.2 - 0.1
+		target = grindingStandbyTemperature + tick.Ambient - referenceTemperature
+	}
+	m.machine.ChasisTemperature += (target-m.machine.ChasisTemperature)*0.2 + m.rand.Float64()*0.6 - 0.3
+
+	telemetry := models.GrindingMachineTelemetryMessage{
+		DeviceType:        "GrindingSensor",
+		PlantName:         m.machine.PlantName,
+		ProductionLine:    m.machine.ProductionLine,
+		MessageTimestamp:  now,
+		ChasisTemperature: math.Round(m.machine.ChasisTemperature*10) / 10,
+		Force:             math.Round(force*10) / 10,
+		Vibration:         math.Round(vibration*10) / 10,
+		GrindingTime:      int(now.Sub(m.machine.BatchStarted).Minutes()),
+		PowerUsage:        math.Round(powerUsage*100) / 100,
+	}
+
+	return toTelemetry(telemetry)
+}
+
+func (m *grindingMachine) ApplyProperty(name string, value interface{}) bool {
+	switch name {
+	case "grindDurationMinutes":
+		if val, ok := getFloatValue(value); ok && val > 0 {
+			m.machine.GrindDurationMinutes = int(val)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *grindingMachine) Commands() []string {
+	return []string{"replaceWheel"}
+}
+
+func (m *grindingMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	switch name {
+	case "replaceWheel":
+		wear := m.machine.WheelWear
+		m.machine.WheelWear = 0.0
+		return map[string]interface{}{"previousWheelWear": wear}, nil
+	}
+	return nil, errUnknownCommand
+}
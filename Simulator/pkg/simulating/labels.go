@@ -0,0 +1,133 @@
+package simulating
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AnomalyFailure     = "failure"     // the machine broke down on its own
+	AnomalyDegradation = "degradation" // the machine health is not Healthy, e.g. while it runs low on oil
+)
+
+type (
+	// AnomalyLabel is the ground truth of an anomaly a device reported, to score anomaly detection against.
+	AnomalyLabel struct {
+		DeviceID string    `json:"deviceId"` // device that reported the anomaly
+		Start    time.Time `json:"start"`    // time of the first message with the anomaly
+		End      time.Time `json:"end"`      // time of the first message without the anomaly
+		Type     string    `json:"type"`     // scenario event type, failure or degradation
+		Signal   string    `json:"signal"`   // telemetry field affected, or * for all of them
+		Reason   string    `json:"reason"`   // downtime reason or machine health, if any
+	}
+
+	// AnomalyLabels writes the anomalies of all devices to a CSV file, or a JSON Lines file if its extension is .jsonl.
+	AnomalyLabels struct {
+		mutex   sync.Mutex
+		file    *os.File
+		csv     *csv.Writer
+		json    *json.Encoder
+		current map[string][]*AnomalyLabel // anomalies of every device that have not ended yet
+	}
+)
+
+// NewAnomalyLabels creates a labels file, replacing the labels of an earlier run.
+func NewAnomalyLabels(path string) (*AnomalyLabels, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0744); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &AnomalyLabels{
+		file:    file,
+		current: map[string][]*AnomalyLabel{},
+	}
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		l.json = json.NewEncoder(file)
+	} else {
+		l.csv = csv.NewWriter(file)
+		l.csv.Write([]string{"deviceId", "start", "end", "type", "signal", "reason"})
+		l.csv.Flush()
+	}
+	return l, nil
+}
+
+// Update records the anomalies a device reports at the given time. Anomalies it no longer reports have ended and are written.
+func (l *AnomalyLabels) Update(deviceID string, anomalies []AnomalyLabel, now time.Time) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return
+	}
+
+	reported := map[string]bool{}
+	for i := range anomalies {
+		reported[anomalies[i].key()] = true
+	}
+	var current []*AnomalyLabel
+	for _, label := range l.current[deviceID] {
+		if reported[label.key()] {
+			delete(reported, label.key())
+			current = append(current, label)
+		} else {
+			label.End = now
+			l.write(label)
+		}
+	}
+	for i := range anomalies {
+		if reported[anomalies[i].key()] {
+			delete(reported, anomalies[i].key())
+			label := anomalies[i]
+			label.DeviceID = deviceID
+			label.Start = now
+			current = append(current, &label)
+		}
+	}
+	l.current[deviceID] = current
+}
+
+// Close ends the anomalies that are still going on at the given time and closes the file.
+func (l *AnomalyLabels) Close(now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return nil
+	}
+
+	for _, labels := range l.current {
+		for _, label := range labels {
+			label.End = now
+			l.write(label)
+		}
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *AnomalyLabels) write(label *AnomalyLabel) {
+	if l.json != nil {
+		l.json.Encode(label)
+		return
+	}
+	l.csv.Write([]string{label.DeviceID, label.Start.Format(time.RFC3339), label.End.Format(time.RFC3339), label.Type, label.Signal, label.Reason})
+	l.csv.Flush()
+}
+
+// key identifies the anomaly among the anomalies of a device.
+func (a *AnomalyLabel) key() string {
+	return a.Type + "/" + a.Signal + "/" + a.Reason
+}
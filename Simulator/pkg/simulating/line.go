@@ -0,0 +1,174 @@
+package simulating
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StateStarved = "Starved" // the machine could run but its upstream buffer is empty
+	StateBlocked = "Blocked" // the machine could run but its downstream buffer is full
+
+	ReasonStarved = "UPSTREAM_STARVED"
+	ReasonBlocked = "DOWNSTREAM_BLOCKED"
+
+	lineThroughputWindow = time.Hour // window over which the line throughput is measured
+)
+
+type (
+	// LineConfig configures a production line as an ordered chain of machines with buffers between them.
+	LineConfig struct {
+		Name  string           `json:"name"`  // name of the line, reported as the production line of its machines
+		Steps []LineStepConfig `json:"steps"` // steps of the line from first to last
+	}
+
+	// LineStepConfig configures a step of a production line.
+	LineStepConfig struct {
+		Machine string  `json:"machine"` // machine kind of the step, e.g. boltMachine
+		Index   int     `json:"index"`   // 1-based index of the machine within the plant, defaults to 1
+		Rate    float64 `json:"rate"`    // units the machine processes per minute while running
+		Buffer  int     `json:"buffer"`  // capacity of the buffer after the step, unused for the last step
+	}
+
+	// ProductionLine moves units between the machines of a line. Its machines run in their own goroutines.
+	ProductionLine struct {
+		name     string
+		steps    []*LineStep
+		buffers  []int // units waiting between a step and the next one
+		mutex    sync.Mutex
+		finished []lineOutput // units finished by the last step within the throughput window
+	}
+
+	// LineStep is the position of a machine in a production line.
+	LineStep struct {
+		line     *ProductionLine
+		cfg      LineStepConfig
+		position int
+		carry    float64 // fraction of a unit processed but not finished yet
+	}
+
+	lineOutput struct {
+		at    time.Time
+		units int
+	}
+)
+
+// NewProductionLine creates a production line with empty buffers.
+func NewProductionLine(cfg *LineConfig) (*ProductionLine, error) {
+	if len(cfg.Steps) == 0 {
+		return nil, fmt.Errorf("production line %s has no steps", cfg.Name)
+	}
+	line := &ProductionLine{
+		name:    cfg.Name,
+		buffers: make([]int, len(cfg.Steps)-1),
+	}
+	for i, stepCfg := range cfg.Steps {
+		if stepCfg.Index == 0 {
+			stepCfg.Index = 1
+		}
+		if stepCfg.Rate <= 0 {
+			return nil, fmt.Errorf("step %d of production line %s needs a rate", i+1, cfg.Name)
+		}
+		if stepCfg.Buffer <= 0 && i < len(cfg.Steps)-1 {
+			return nil, fmt.Errorf("step %d of production line %s needs a buffer", i+1, cfg.Name)
+		}
+		line.steps = append(line.steps, &LineStep{line: line, cfg: stepCfg, position: i})
+	}
+	return line, nil
+}
+
+// Name gets the name of the line.
+func (l *ProductionLine) Name() string {
+	return l.name
+}
+
+// Step gets the step of the n-th machine of a kind, or nil if the machine is not part of the line.
+func (l *ProductionLine) Step(kind string, index int) *LineStep {
+	for _, step := range l.steps {
+		if strings.EqualFold(step.cfg.Machine, kind) && step.cfg.Index == index {
+			return step
+		}
+	}
+	return nil
+}
+
+// Process gets the units the machine can process in its running time at the given load, as far as the buffers allow.
+// It returns the units, the time the machine was actually producing, and Starved or Blocked if the buffers limited the
+// machine. The units stay in the buffers until Move moves those the machine actually made.
+func (s *LineStep) Process(runTime time.Duration, load float64, now time.Time) (int, time.Duration, string) {
+	l := s.line
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	s.carry += s.cfg.Rate * load * runTime.Minutes()
+	capacity := int(s.carry)
+	units := capacity
+	constraint := ""
+	if s.position > 0 && l.buffers[s.position-1] < units {
+		units = l.buffers[s.position-1]
+		constraint = StateStarved
+	}
+	if s.position < len(l.steps)-1 && s.cfg.Buffer-l.buffers[s.position] < units {
+		units = s.cfg.Buffer - l.buffers[s.position]
+		constraint = StateBlocked
+	}
+	// capacity that could not be used is lost, only the fraction of a unit carries over
+	s.carry -= float64(capacity)
+
+	producing := runTime
+	if capacity > 0 {
+		producing = time.Duration(float64(runTime) * float64(units) / float64(capacity))
+	}
+	return units, producing, constraint
+}
+
+// Move moves the units the machine made from the upstream buffer to the downstream buffer, at most the units Process
+// allowed in the same step. The buffers stay within their limits meanwhile, as only the machine takes from the buffer
+// before it and adds to the buffer after it.
+func (s *LineStep) Move(units int, now time.Time) {
+	l := s.line
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if units <= 0 {
+		return
+	}
+	if s.position > 0 {
+		l.buffers[s.position-1] -= units
+	}
+	if s.position < len(l.steps)-1 {
+		l.buffers[s.position] += units
+	} else {
+		l.finished = append(l.finished, lineOutput{at: now, units: units})
+	}
+}
+
+// BufferLevel gets the units waiting in the buffer after the step, or zero for the last step.
+func (s *LineStep) BufferLevel() int {
+	s.line.mutex.Lock()
+	defer s.line.mutex.Unlock()
+	if s.position == len(s.line.steps)-1 {
+		return 0
+	}
+	return s.line.buffers[s.position]
+}
+
+// Throughput gets the units the line finished in the last hour.
+func (s *LineStep) Throughput(now time.Time) int {
+	l := s.line
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	units := 0
+	kept := l.finished[:0]
+	for _, output := range l.finished {
+		if now.Sub(output.at) < lineThroughputWindow {
+			kept = append(kept, output)
+			units += output.units
+		}
+	}
+	l.finished = kept
+	return units
+}
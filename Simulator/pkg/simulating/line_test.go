@@ -0,0 +1,83 @@
+package simulating
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+func TestLineFollowsLoad(t *testing.T) {
+	for _, test := range []struct {
+		load  float64
+		units int
+	}{
+		{load: 1, units: 100},
+		{load: 0.5, units: 50},
+	} {
+		line, err := NewProductionLine(&LineConfig{
+			Name: "Line A",
+			Steps: []LineStepConfig{
+				{Machine: "boltMachine", Rate: 10, Buffer: 1000},
+				{Machine: "grindingMachine", Rate: 20},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Date(2022, 5, 24, 9, 0, 0, 0, time.UTC)
+		units, producing, constraint := line.Step("boltMachine", 1).Process(10*time.Minute, test.load, now)
+		if units != test.units || producing != 10*time.Minute || constraint != "" {
+			t.Errorf("load %v: Process = %d units, %v, %q, want %d units, 10m0s and no constraint", test.load, units, producing, constraint, test.units)
+		}
+		line.Step("boltMachine", 1).Move(units, now)
+		if level := line.Step("boltMachine", 1).BufferLevel(); level != test.units {
+			t.Errorf("load %v: BufferLevel = %d, want %d", test.load, level, test.units)
+		}
+	}
+}
+
+func TestLineMovesPartsMade(t *testing.T) {
+	line, err := NewProductionLine(&LineConfig{
+		Name: "Line A",
+		Steps: []LineStepConfig{
+			{Machine: "boltMachine", Rate: 10, Buffer: 1000},
+			{Machine: "grindingMachine", Rate: 20},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &MachineSpec{
+		DeviceID: "Test-BoltMachine-1",
+		Config:   &MachineConfig{Format: "json", State: &StateConfig{}},
+		Rand:     NewDeviceRand(1, "Test-BoltMachine-1"),
+		Line:     line.Step("boltMachine", 1),
+		App:      &models.CentralApplication{},
+	}
+	machine, err := newBoltMachine(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the machine runs the whole step, but it is out of oil and in Error
+	machine.(*boltMachine).machine.OilLevel = 5
+	device := NewDevice(context.Background(), spec, machine)
+	device.telemetryFrequency = 60 * 60
+
+	payload, err := device.getTelemetryMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var telemetry map[string]interface{}
+	if err := json.Unmarshal(payload, &telemetry); err != nil {
+		t.Fatal(err)
+	}
+	if health := telemetry["machineHealth"]; health != "Error" {
+		t.Errorf("machineHealth = %v, want Error", health)
+	}
+	if parts, level := telemetry["totalPartsMade"], telemetry["bufferLevel"]; parts != 0.0 || level != 0.0 {
+		t.Errorf("totalPartsMade = %v and bufferLevel = %v, want 0 and 0", parts, level)
+	}
+}
@@ -0,0 +1,104 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+type (
+	// LoadConfig configures the load profile of a machine kind, the share of its capacity the plant asks for over
+	// the day and the week, e.g. a slow start into the shift or a quiet night shift.
+	LoadConfig struct {
+		Hourly   []float64          `json:"hourly"`   // load by local hour of the day, 24 values interpolated between the hours, full load if empty
+		Weekdays map[string]float64 `json:"weekdays"` // load by weekday, e.g. Mon, full load on the other days
+		Shifts   map[string]float64 `json:"shifts"`   // load by shift name, e.g. Night, full load in the other shifts
+		RampUp   time.Duration      `json:"rampUp"`   // time after the start of a shift in which the load ramps up to full load
+		RampFrom float64            `json:"rampFrom"` // load at the start of a shift
+		Dips     []LoadDip          `json:"dips"`     // daily dips in the load, e.g. around lunch
+	}
+
+	// LoadDip lowers the load at the same local time every day.
+	LoadDip struct {
+		Start    string        `json:"start"`    // local start time, HH:MM
+		Duration time.Duration `json:"duration"` // length of the dip
+		Days     []string      `json:"days"`     // weekdays the dip applies to, every day if empty
+		Load     float64       `json:"load"`     // load during the dip
+	}
+)
+
+// validate checks the load profile, so that a misconfigured profile fails at startup.
+func (c *LoadConfig) validate() error {
+	if len(c.Hourly) != 0 && len(c.Hourly) != 24 {
+		return fmt.Errorf("hourly load profile needs 24 values, got %d", len(c.Hourly))
+	}
+	for day := range c.Weekdays {
+		if !isWeekday(day) {
+			return fmt.Errorf("load profile has invalid weekday %s", day)
+		}
+	}
+	for _, dip := range c.Dips {
+		if _, ok := parseMinuteOfDay(dip.Start); !ok || dip.Duration <= 0 {
+			return fmt.Errorf("load profile has invalid dip %s", dip.Start)
+		}
+	}
+	return nil
+}
+
+// getLoad gets the load at the given local time in the given shift, which multiplies the output and the energy of a
+// running machine. It is 1 without a load profile.
+func (c *LoadConfig) getLoad(local time.Time, shift *Shift) float64 {
+	if c == nil {
+		return 1
+	}
+	load := 1.0
+	if len(c.Hourly) == 24 {
+		hour := float64(local.Hour()) + float64(local.Minute())/60
+		from := int(hour)
+		load *= c.Hourly[from] + (c.Hourly[(from+1)%24]-c.Hourly[from])*(hour-float64(from))
+	}
+	for day, dayLoad := range c.Weekdays {
+		if appliesToDay([]string{day}, local.Weekday()) {
+			load *= dayLoad
+		}
+	}
+	if shift != nil {
+		for name, shiftLoad := range c.Shifts {
+			if strings.EqualFold(name, shift.Name) {
+				load *= shiftLoad
+			}
+		}
+		if since := local.Sub(shift.Start); c.RampUp > 0 && since >= 0 && since < c.RampUp {
+			load *= c.RampFrom + (1-c.RampFrom)*since.Seconds()/c.RampUp.Seconds()
+		}
+	}
+	minute := local.Hour()*60 + local.Minute()
+	for _, dip := range c.Dips {
+		start, _ := parseMinuteOfDay(dip.Start)
+		// a dip that started before midnight applies to the day it started on
+		after := (minute - start + 24*60) % (24 * 60)
+		day := local
+		if minute < start {
+			day = local.AddDate(0, 0, -1)
+		}
+		if time.Duration(after)*time.Minute < dip.Duration && appliesToDay(dip.Days, day.Weekday()) {
+			load *= dip.Load
+		}
+	}
+	return math.Max(0, load)
+}
+
+// getLoadPower scales the power a running machine draws above its standby power by the load of the step.
+func getLoadPower(power float64, standbyPower float64, load float64) float64 {
+	return standbyPower + (power-standbyPower)*load
+}
+
+func isWeekday(day string) bool {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if appliesToDay([]string{day}, weekday) {
+			return true
+		}
+	}
+	return false
+}
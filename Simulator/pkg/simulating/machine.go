@@ -0,0 +1,180 @@
+package simulating
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+type (
+	// Machine is a simulated machine whose telemetry is streamed by a device.
+	Machine interface {
+		// ModelID gets the device model ID the machine is provisioned as.
+		ModelID() string
+		// NextTelemetry advances the machine by one tick and returns the telemetry values to send.
+		NextTelemetry(tick *Tick) (models.Telemetry, error)
+		// ApplyProperty applies a writable twin property, returning false if the machine does not know it.
+		ApplyProperty(name string, value interface{}) bool
+		// Commands gets the names of the direct methods the machine handles.
+		Commands() []string
+		// HandleCommand executes a direct method and returns its response payload.
+		HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error)
+	}
+
+	// Tick describes one simulation step of a machine.
+	Tick struct {
+		Now          time.Time      // time of the step in UTC.
+		Local        time.Time      // time of the step in the time zone of the plant, for shifts and daily profiles.
+		Interval     time.Duration  // time elapsed since the previous step.
+		ShiftNumber  int            // employee shift the step falls in, 0 between the shifts of a calendar.
+		ShiftID      string         // unique ID of the shift, empty between the shifts of a calendar.
+		ShiftName    string         // name of the shift, empty between the shifts of a calendar.
+		BatchNumber  int            // production batch the step falls in.
+		BatchID      string         // unique ID of the batch.
+		Product      *ProductConfig // product the machine makes in the batch, nil if the plan has none for the machine.
+		Load         float64        // share of its capacity the machine runs at, from the load profile of its kind, 1 without one.
+		Ambient      float64        // temperature around the machine in °C, from the floor it is on or the weather at the plant.
+		State        string         // state of the machine at the end of the step, e.g. Running or Off.
+		RunTime      time.Duration  // time the machine was running during the step.
+		PlannedStop  time.Duration  // time the machine was in planned downtime during the step.
+		StateMachine *StateMachine  // state machine of the device, which the machine may hold in a downtime state.
+		Line         *LineStep      // step of the production line the machine belongs to, nil if it is not in a line.
+		LineOutput   int            // units the line allows the machine to process in the step, which it lowers to the units it made.
+	}
+
+	// MachineSpec describes a machine to be created by a MachineFactory.
+	MachineSpec struct {
+		Kind           string                     // registered machine kind, e.g. boltMachine.
+		DeviceID       string                     // id of the device simulating the machine.
+		PlantName      string                     // plant the machine belongs to.
+		ProductionLine string                     // production line the machine belongs to.
+		Index          int                        // 1-based index of the machine within its plant.
+		Config         *MachineConfig             // machine configuration block of the plant.
+		Line           *LineStep                  // step of the production line the machine belongs to, if any.
+		Rand           *rand.Rand                 // random source of the device, see NewDeviceRand.
+		Scenario       *Scenario                  // scenario of injected faults and events the device plays, if any.
+		Labels         *AnomalyLabels             // file the device writes its anomalies to, if any.
+		Carbon         *CarbonProfile             // carbon intensity of the grid of the plant, the default intensity if nil.
+		Tariff         *Tariff                    // electricity tariff of the plant, the default price if nil.
+		Meter          *PlantMeter                // plant meter the energy of the machine adds up in, if any.
+		Floor          *Floor                     // floor the machine gives off its heat to, if any.
+		Weather        *Weather                   // weather at the plant, the default daily cycle if nil.
+		Calendar       *ShiftCalendar             // shift calendar of the plant, shifts of the shiftDurationHours property if nil.
+		Location       *time.Location             // time zone of the plant, UTC if nil.
+		Products       []*ProductConfig           // products the machine makes in turn, a batch each, if any.
+		App            *models.CentralApplication // IoT Central application the machine connects to.
+	}
+
+	// MachineFactory creates a new machine from its spec.
+	MachineFactory func(spec *MachineSpec) (Machine, error)
+)
+
+var (
+	machineFactories = map[string]MachineFactory{}
+
+	errUnknownCommand = errors.New("unknown command")
+)
+
+// RegisterMachine registers a machine kind under the name of its configuration block, e.g. boltMachine. It panics if
+// the kind is already registered, as the built-in kinds register at init; check machine types of the configuration
+// with registeredMachineKind first.
+func RegisterMachine(kind string, factory MachineFactory) {
+	if registered, ok := registeredMachineKind(kind); ok {
+		panic(fmt.Sprintf("machine kind %s is already registered as %s", kind, registered))
+	}
+	machineFactories[kind] = factory
+}
+
+// registeredMachineKind gets the registered machine kind with the given name, ignoring case as the configuration
+// blocks do.
+func registeredMachineKind(kind string) (string, bool) {
+	for registered := range machineFactories {
+		if strings.EqualFold(registered, kind) {
+			return registered, true
+		}
+	}
+	return "", false
+}
+
+// MachineKinds gets the names of all registered machine kinds in sorted order.
+func MachineKinds() []string {
+	kinds := make([]string, 0, len(machineFactories))
+	for kind := range machineFactories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewMachine creates a machine of the registered kind given in the spec.
+func NewMachine(spec *MachineSpec) (Machine, error) {
+	factory, ok := machineFactories[spec.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown machine kind %s", spec.Kind)
+	}
+	if spec.Rand == nil {
+		spec.Rand = NewDeviceRand(time.Now().UnixNano(), spec.DeviceID)
+	}
+	return factory(spec)
+}
+
+// NewDeviceRand creates the random source of a device, derived from the simulation seed and the device ID,
+// so that every device gets its own reproducible sequence.
+func NewDeviceRand(seed int64, deviceID string) *rand.Rand {
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%d/%s", seed, deviceID)
+	return rand.New(rand.NewSource(int64(hash.Sum64())))
+}
+
+// sensorConfig gets the configuration of a device that measures the plant rather than being a machine. It is always
+// running, it never fails or changes over and it does not stop between shifts.
+func sensorConfig(format string) *MachineConfig {
+	return &MachineConfig{
+		Count:    1,
+		Format:   format,
+		State:    &StateConfig{},
+		alwaysOn: true,
+	}
+}
+
+// MachineDeviceID gets the device ID of the n-th machine of a kind in a plant, e.g. Amsterdam-BoltMachine-1.
+func MachineDeviceID(plantName string, kind string, index int) string {
+	name := kind
+	if name != "" {
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	return fmt.Sprintf("%s-%s-%d", plantName, name, index)
+}
+
+// toTelemetry converts a telemetry message struct into named telemetry values.
+func toTelemetry(message interface{}) (models.Telemetry, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var telemetry models.Telemetry
+	if err = json.Unmarshal(body, &telemetry); err != nil {
+		return nil, err
+	}
+	return telemetry, nil
+}
+
+// getFloatValue gets a numeric twin or command value as float64.
+func getFloatValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
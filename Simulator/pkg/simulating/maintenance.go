@@ -0,0 +1,28 @@
+package simulating
+
+import "time"
+
+const (
+	MaintenanceCommandRefill    = "COMMAND_OIL_REFILL"    // oil refilled through the refillOil command
+	MaintenanceScheduledRefill  = "SCHEDULED_OIL_REFILL"  // oil refilled by the maintenance schedule
+	MaintenanceTechnicianRefill = "TECHNICIAN_OIL_REFILL" // oil refilled by a technician called to a dry machine
+)
+
+// MaintenanceConfig configures the oil consumption and maintenance of a machine kind.
+type MaintenanceConfig struct {
+	OilPerPart     float64       `json:"oilPerPart"`     // oil level in percent used per part made
+	ScheduleEvery  time.Duration `json:"scheduleEvery"`  // time between scheduled oil refills, none if zero
+	RefillDuration time.Duration `json:"refillDuration"` // time a scheduled refill stops the machine
+	Technician     bool          `json:"technician"`     // a technician refills the oil when the machine runs dry
+	ResponseTime   Distribution  `json:"responseTime"`   // time until the technician has refilled a dry machine
+}
+
+// defaultMaintenanceConfig gets the maintenance configuration used for machine kinds without one.
+func defaultMaintenanceConfig() *MaintenanceConfig {
+	return &MaintenanceConfig{
+		OilPerPart:     0.001,
+		RefillDuration: 10 * time.Minute,
+		Technician:     true,
+		ResponseTime:   Distribution{Type: "exponential", Mean: 45 * time.Minute},
+	}
+}
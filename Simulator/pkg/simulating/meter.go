@@ -0,0 +1,218 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+const (
+	PlantMeterKind = "plantMeter" // kind of the plant meter device, used in its device ID
+
+	baseLoadNoise = 0.03 // relative noise on the power of a base load
+)
+
+type (
+	// Aggregator is implemented by machines that add up the values of other machines, like the plant meter, so that a
+	// scenario event for a whole plant, which already changed those machines, does not change the sum again.
+	Aggregator interface {
+		// Aggregates tells whether the telemetry field adds up the values of other machines.
+		Aggregates(field string) bool
+	}
+
+	// MeterConfig configures the main energy meter of a plant.
+	MeterConfig struct {
+		Format    string           `json:"format"`    // telemetry payload format, json or opcua
+		BaseLoads []BaseLoadConfig `json:"baseLoads"` // loads of the site besides the simulated machines, defaults apply if not set
+	}
+
+	// BaseLoadConfig configures a load of the site that is not a simulated machine, e.g. HVAC or lighting.
+	BaseLoadConfig struct {
+		Name   string    `json:"name"`   // name of the load, e.g. hvac
+		Power  float64   `json:"power"`  // power at full load in kilowatt
+		Daily  []float64 `json:"daily"`  // share of the full load for every local hour of the day, full load if empty
+		Weekly []float64 `json:"weekly"` // share of the full load for every day of the week from Monday, full load if empty
+	}
+
+	// PlantMeter adds up the energy the machines of a plant use, for the plant meter device to read.
+	PlantMeter struct {
+		cfg   *MeterConfig
+		total energyTotal // energy the machines used since the previous read
+	}
+
+	// energyTotal adds up the energy machines report from their own devices until it is read.
+	energyTotal struct {
+		mutex sync.Mutex
+		kwh   float64
+	}
+
+	plantMeterMachine struct {
+		modelID   string // device model ID of plant meters.
+		plantName string // plant the meter measures.
+		meter     *PlantMeter
+		rand      *rand.Rand // random source of the device
+		reading   float64    // energy used since the simulator started
+	}
+)
+
+// defaultBaseLoads gets the base loads of a plant meter without any: HVAC, lighting and compressors that follow
+// the working day and are lower during the weekend.
+func defaultBaseLoads() []BaseLoadConfig {
+	dayShift := func(night float64, day float64) []float64 {
+		profile := make([]float64, 24)
+		for h := range profile {
+			profile[h] = night
+			if h >= 6 && h < 22 {
+				profile[h] = day
+			}
+		}
+		return profile
+	}
+	weekend := func(share float64) []float64 {
+		return []float64{1, 1, 1, 1, 1, share, share}
+	}
+	return []BaseLoadConfig{
+		{Name: "hvac", Power: 40, Daily: dayShift(0.4, 1), Weekly: weekend(0.6)},
+		{Name: "lighting", Power: 15, Daily: dayShift(0.1, 1), Weekly: weekend(0.2)},
+		{Name: "compressors", Power: 25, Daily: dayShift(0.3, 0.9), Weekly: weekend(0.3)},
+	}
+}
+
+// NewPlantMeter creates the meter of a plant, or nil if the plant has no meter configuration.
+func NewPlantMeter(cfg *MeterConfig) (*PlantMeter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.BaseLoads) == 0 {
+		cfg.BaseLoads = defaultBaseLoads()
+	}
+	for _, load := range cfg.BaseLoads {
+		if load.Name == "" {
+			return nil, fmt.Errorf("base load needs a name")
+		}
+		if len(load.Daily) != 0 && len(load.Daily) != 24 {
+			return nil, fmt.Errorf("daily profile of base load %s needs 24 hours", load.Name)
+		}
+		if len(load.Weekly) != 0 && len(load.Weekly) != 7 {
+			return nil, fmt.Errorf("weekly profile of base load %s needs 7 days", load.Name)
+		}
+	}
+	return &PlantMeter{cfg: cfg}, nil
+}
+
+// MachineConfig gets the configuration of the meter device.
+func (m *PlantMeter) MachineConfig() *MachineConfig {
+	return sensorConfig(m.cfg.Format)
+}
+
+// Add adds the energy a machine used over a step.
+func (m *PlantMeter) Add(kwh float64) {
+	if m == nil {
+		return
+	}
+	m.total.add(kwh)
+}
+
+func (t *energyTotal) add(kwh float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.kwh += kwh
+}
+
+// take gets the energy added since the previous take.
+func (t *energyTotal) take() float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	kwh := t.kwh
+	t.kwh = 0
+	return kwh
+}
+
+// NewPlantMeterMachine creates the device side of a plant meter, which reports the energy of the machines that add
+// to the meter plus the base loads of the plant.
+func NewPlantMeterMachine(spec *MachineSpec, meter *PlantMeter) Machine {
+	if spec.Rand == nil {
+		spec.Rand = NewDeviceRand(time.Now().UnixNano(), spec.DeviceID)
+	}
+	return &plantMeterMachine{
+		modelID:   spec.App.PlantMeterModelID,
+		plantName: spec.PlantName,
+		meter:     meter,
+		rand:      spec.Rand,
+	}
+}
+
+func (m *plantMeterMachine) ModelID() string {
+	return m.modelID
+}
+
+// NextTelemetry reads the energy of the machines and adds the base loads of the plant over the step.
+func (m *plantMeterMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	machineKwh := m.meter.total.take()
+	// the base loads are sampled in the middle of the step
+	at := tick.Local.Add(-tick.Interval / 2)
+	baseLoads := map[string]float64{}
+	baseLoadKwh := 0.0
+	for _, load := range m.meter.cfg.BaseLoads {
+		power := load.Power * load.getShare(at) * (1 + (m.rand.Float64()*2-1)*baseLoadNoise)
+		kwh := power * tick.Interval.Hours()
+		baseLoads[load.Name] = math.Round(kwh*1000) / 1000
+		baseLoadKwh += kwh
+	}
+	kwh := machineKwh + baseLoadKwh
+	m.reading += kwh
+
+	power := 0.0
+	if tick.Interval > 0 {
+		power = kwh / tick.Interval.Hours()
+	}
+	telemetry := models.PlantMeterTelemetryMessage{
+		PlantName:        m.plantName,
+		MessageTimestamp: tick.Now,
+		Kwh:              math.Round(kwh*1000) / 1000,
+		MachineKwh:       math.Round(machineKwh*1000) / 1000,
+		BaseLoadKwh:      math.Round(baseLoadKwh*1000) / 1000,
+		BaseLoads:        baseLoads,
+		Power:            math.Round(power*100) / 100,
+		MeterReading:     math.Round(m.reading*1000) / 1000,
+	}
+	return toTelemetry(telemetry)
+}
+
+// Aggregates tells whether the telemetry field adds up the energy of the machines.
+func (m *plantMeterMachine) Aggregates(field string) bool {
+	switch field {
+	case "kwh", "machineKwh", "power", "meterReading":
+		return true
+	}
+	return false
+}
+
+func (m *plantMeterMachine) ApplyProperty(name string, value interface{}) bool {
+	return false
+}
+
+func (m *plantMeterMachine) Commands() []string {
+	return nil
+}
+
+func (m *plantMeterMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return nil, errUnknownCommand
+}
+
+// getShare gets the share of the full load at the given time.
+func (l *BaseLoadConfig) getShare(at time.Time) float64 {
+	share := 1.0
+	if len(l.Daily) == 24 {
+		share *= l.Daily[at.Hour()]
+	}
+	if len(l.Weekly) == 7 {
+		// the week starts on Monday
+		share *= l.Weekly[(int(at.Weekday())+6)%7]
+	}
+	return share
+}
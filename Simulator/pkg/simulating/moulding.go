@@ -0,0 +1,176 @@
+package simulating
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+const (
+	mouldingPhaseHeating   = "Heating"   // heaters on until the mould reaches its setpoint
+	mouldingPhaseInjection = "Injection" // material is injected while the mould is held at setpoint
+	mouldingPhaseCooling   = "Cooling"   // heaters off, the part cools down until it can be ejected
+	mouldingPhaseStopped   = "Stopped"   // the machine is not running, the cycle is paused
+
+	mouldingHeaterTemperature  = 260.0 // temperature the mould would settle at with the heaters on
+	mouldingCoolantTemperature = 30.0  // temperature the mould would settle at with the chiller on
+	mouldingHeatingMinutes     = 8.0   // time constant of heating up the mould
+	mouldingCoolingMinutes     = 6.0   // time constant of cooling down the mould
+
+	mouldingHeatingPower   = 48.0 // heater bank plus idle hydraulics in kilowatt
+	mouldingInjectionPower = 30.0 // injection hydraulics plus heaters holding the setpoint in kilowatt
+	mouldingCoolingPower   = 8.0  // chiller pump plus idle hydraulics in kilowatt
+	mouldingStandbyPower   = 3.0  // chiller pump of a stopped machine in kilowatt
+
+	mouldingStep = 5 * time.Second // integration step of the thermal model
+)
+
+type mouldingMachine struct {
+	modelID      string                  // device model ID of moulding machines.
+	rand         *rand.Rand              // random source of the device
+	standbyPower float64                 // power drawn while stopped or switched off in kilowatt
+	machine      *models.MouldingMachine // moulding machine state
+}
+
+func init() {
+	RegisterMachine("mouldingMachine", newMouldingMachine)
+	energyDefaults["mouldingMachine"] = EnergyConfig{StandbyPower: mouldingStandbyPower}
+}
+
+func newMouldingMachine(spec *MachineSpec) (Machine, error) {
+	return &mouldingMachine{
+		modelID:      spec.App.MouldingMachineModelID,
+		standbyPower: getStandbyPower(spec.Config, mouldingStandbyPower),
+		rand:         spec.Rand,
+		machine: &models.MouldingMachine{
+			PlantName:           spec.PlantName,
+			ProductionLine:      spec.ProductionLine,
+			SetpointTemperature: 220,
+			EjectTemperature:    80,
+			InjectionSeconds:    120,
+			ChasisTemperature:   80,
+			Format:              spec.Config.Format,
+		},
+	}, nil
+}
+
+func (m *mouldingMachine) ModelID() string {
+	return m.modelID
+}
+
+// NextTelemetry runs the mould cycle since the previous message and builds the telemetry message of the moulding machine.
+func (m *mouldingMachine) NextTelemetry(tick *Tick) (models.Telemetry, error) {
+	now := tick.Now
+	if m.machine.LastUpdate.IsZero() {
+		m.machine.Phase = mouldingPhaseHeating
+		m.machine.PhaseStarted = now
+		m.machine.LastUpdate = now
+	}
+
+	// integrate the mould temperature and the energy used over the running time, the mould cools down for the rest;
+	// below full load the machine only cycles for its share of the running time
+	energy := 0.0
+	elapsed := now.Sub(m.machine.LastUpdate)
+	runTime := time.Duration(float64(tick.RunTime) * math.Min(1, tick.Load))
+	if runTime > elapsed {
+		runTime = elapsed
+	}
+	for t := time.Duration(0); t < elapsed; t += mouldingStep {
+		step := mouldingStep
+		if elapsed-t < step {
+			step = elapsed - t
+		}
+		if t < runTime {
+			energy += m.stepMouldingCycle(m.machine.LastUpdate.Add(t), step, tick.Ambient)
+		} else {
+			m.machine.ChasisTemperature += (mouldingCoolantTemperature - m.machine.ChasisTemperature) * step.Minutes() / mouldingCoolingMinutes
+			energy += m.standbyPower * step.Hours()
+		}
+	}
+	m.machine.LastUpdate = now
+
+	// report the average power over the interval so short heating peaks are not missed
+	powerUsage := m.getMouldingPhasePower(m.machine.Phase)
+	phase := m.machine.Phase
+	if tick.State != StateRunning {
+		powerUsage = m.standbyPower
+		phase = mouldingPhaseStopped
+	}
+	if elapsed > 0 {
+		powerUsage = energy / elapsed.Hours()
+	}
+	powerUsage += m.rand.Float64()*0.6 - 0.3
+
+	telemetry := models.MouldingMachineTelemetryMessage{
+		DeviceType:        "MouldingSensor",
+		PlantName:         m.machine.PlantName,
+		ProductionLine:    m.machine.ProductionLine,
+		MessageTimestamp:  now,
+		CyclePhase:        phase,
+		ChasisTemperature: math.Round((m.machine.ChasisTemperature+m.rand.Float64()-0.5)*10) / 10,
+		PowerUsage:        math.Round(powerUsage*100) / 100,
+	}
+
+	return toTelemetry(telemetry)
+}
+
+func (m *mouldingMachine) ApplyProperty(name string, value interface{}) bool {
+	switch name {
+	case "setpointTemperature":
+		if val, ok := getFloatValue(value); ok && val > m.machine.EjectTemperature && val < mouldingHeaterTemperature {
+			m.machine.SetpointTemperature = val
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mouldingMachine) Commands() []string {
+	return nil
+}
+
+func (m *mouldingMachine) HandleCommand(name string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return nil, errUnknownCommand
+}
+
+// stepMouldingCycle advances the mould cycle by one integration step and returns the energy used in kWh. The chiller
+// works harder at a higher ambient temperature.
+func (m *mouldingMachine) stepMouldingCycle(at time.Time, step time.Duration, ambient float64) float64 {
+	switch m.machine.Phase {
+	case mouldingPhaseHeating:
+		m.machine.ChasisTemperature += (mouldingHeaterTemperature - m.machine.ChasisTemperature) * step.Minutes() / mouldingHeatingMinutes
+		if m.machine.ChasisTemperature >= m.machine.SetpointTemperature {
+			m.machine.Phase = mouldingPhaseInjection
+			m.machine.PhaseStarted = at.Add(step)
+		}
+		return mouldingHeatingPower * step.Hours()
+	case mouldingPhaseInjection:
+		m.machine.ChasisTemperature = m.machine.SetpointTemperature
+		if at.Add(step).Sub(m.machine.PhaseStarted) >= time.Duration(m.machine.InjectionSeconds)*time.Second {
+			m.machine.Phase = mouldingPhaseCooling
+			m.machine.PhaseStarted = at.Add(step)
+		}
+		return mouldingInjectionPower * step.Hours()
+	default:
+		m.machine.ChasisTemperature += (mouldingCoolantTemperature - m.machine.ChasisTemperature) * step.Minutes() / mouldingCoolingMinutes
+		if m.machine.ChasisTemperature <= m.machine.EjectTemperature {
+			m.machine.Phase = mouldingPhaseHeating
+			m.machine.PhaseStarted = at.Add(step)
+		}
+		return mouldingCoolingPower * getCoolingFactor(ambient) * step.Hours()
+	}
+}
+
+// getMouldingPhasePower gets the nominal power draw of a moulding machine in the given phase.
+func (m *mouldingMachine) getMouldingPhasePower(phase string) float64 {
+	switch phase {
+	case mouldingPhaseHeating:
+		return mouldingHeatingPower
+	case mouldingPhaseInjection:
+		return mouldingInjectionPower
+	default:
+		return mouldingCoolingPower
+	}
+}
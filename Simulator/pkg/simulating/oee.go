@@ -0,0 +1,118 @@
+package simulating
+
+import (
+	"math"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+type (
+	// PartCounter is implemented by machines that count the parts they make, so that the device can rate their
+	// performance against the ideal cycle time.
+	PartCounter interface {
+		// IdealCycleTime gets the ideal time to make one part in the given step.
+		IdealCycleTime(tick *Tick) time.Duration
+	}
+
+	// oeeCounter adds up the times and parts the OEE of a window is computed from.
+	oeeCounter struct {
+		planned time.Duration // planned production time, the time the machine was on and not in planned downtime
+		run     time.Duration // time the machine was running
+		ideal   time.Duration // time the parts made would take at the ideal cycle time
+		parts   int           // parts made
+		good    int           // parts made that are not defective
+		rated   bool          // the machine counts its parts, so the performance can be rated
+		counted bool          // the machine reports its parts, so the quality can be rated
+	}
+
+	oeeSample struct {
+		at      time.Time
+		counter oeeCounter
+	}
+
+	// oeeTracker tracks the OEE of a machine over the current shift, the current batch and the last hour.
+	oeeTracker struct {
+		shiftID string
+		shift   oeeCounter
+		batchID string
+		batch   oeeCounter
+		hour    []oeeSample
+	}
+)
+
+// add adds a step of the machine, and the parts it reported in its telemetry, if any, to the windows.
+func (t *oeeTracker) add(tick *Tick, telemetry models.Telemetry, cycleTime time.Duration) {
+	step := oeeCounter{run: tick.RunTime}
+	if tick.State != StateOff {
+		step.planned = maxDuration(0, tick.Interval-tick.PlannedStop)
+	}
+	if parts, ok := getFloatValue(telemetry["totalPartsMade"]); ok {
+		defects, _ := getFloatValue(telemetry["defectivePartsMade"])
+		step.parts = int(parts)
+		step.good = int(math.Max(0, parts-defects))
+		step.counted = true
+		if cycleTime > 0 {
+			step.ideal = cycleTime * time.Duration(step.parts)
+			step.rated = true
+		}
+	}
+
+	if tick.ShiftID != t.shiftID {
+		t.shiftID = tick.ShiftID
+		t.shift = oeeCounter{}
+	}
+	if tick.BatchID != t.batchID {
+		t.batchID = tick.BatchID
+		t.batch = oeeCounter{}
+	}
+	t.shift.add(step)
+	t.batch.add(step)
+	t.hour = append(t.hour, oeeSample{at: tick.Now, counter: step})
+	for len(t.hour) > 0 && !t.hour[0].at.After(tick.Now.Add(-time.Hour)) {
+		t.hour = t.hour[1:]
+	}
+}
+
+// addTelemetry adds the OEE of the windows to the telemetry, e.g. shiftAvailability and hourOee, in percent.
+func (t *oeeTracker) addTelemetry(telemetry models.Telemetry) {
+	var hour oeeCounter
+	for _, sample := range t.hour {
+		hour.add(sample.counter)
+	}
+	t.shift.addTelemetry(telemetry, "shift")
+	t.batch.addTelemetry(telemetry, "batch")
+	hour.addTelemetry(telemetry, "hour")
+}
+
+func (c *oeeCounter) add(step oeeCounter) {
+	c.planned += step.planned
+	c.run += step.run
+	c.ideal += step.ideal
+	c.parts += step.parts
+	c.good += step.good
+	c.rated = c.rated || step.rated
+	c.counted = c.counted || step.counted
+}
+
+// addTelemetry adds the availability, performance, quality and OEE of the window to the telemetry. A factor without
+// a base, like the performance of a window without running time, is left out, and so is the OEE.
+func (c *oeeCounter) addTelemetry(telemetry models.Telemetry, window string) {
+	oee, complete := 1.0, true
+	factor := func(name string, value float64, ok bool) {
+		if !ok {
+			complete = false
+			return
+		}
+		// the factors are capped at 100%, e.g. for a machine in a production line that outruns its ideal cycle time
+		value = math.Min(1, value)
+		oee *= value
+		telemetry[window+name] = math.Round(value*10000) / 100
+	}
+	factor("Availability", c.run.Seconds()/c.planned.Seconds(), c.planned > 0)
+	factor("Performance", c.ideal.Seconds()/c.run.Seconds(), c.rated && c.run > 0)
+	factor("Quality", float64(c.good)/float64(c.parts), c.counted && c.parts > 0)
+	if complete {
+		telemetry[window+"Oee"] = math.Round(oee*10000) / 100
+	}
+}
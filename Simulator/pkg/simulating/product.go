@@ -0,0 +1,62 @@
+package simulating
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+type (
+	// ProductConfig configures a product of the plant's catalogue.
+	ProductConfig struct {
+		ID            string        `json:"id"`            // product ID reported by the machines making it, e.g. BOLT-M8
+		Name          string        `json:"name"`          // name of the product
+		CycleTime     time.Duration `json:"cycleTime"`     // ideal time to make one part
+		EnergyPerPart float64       `json:"energyPerPart"` // energy used to make one part in kWh, the energy model of the machine if zero
+		DefectRate    float64       `json:"defectRate"`    // base share of the parts that are defective, e.g. 0.01 for 1%
+		OilPerPart    float64       `json:"oilPerPart"`    // oil level in percent used per part, the maintenance configuration if zero
+	}
+
+	// PlanConfig assigns the products a machine makes, one product per batch.
+	PlanConfig struct {
+		Machine  string   `json:"machine"`  // machine kind, e.g. boltMachine
+		Index    int      `json:"index"`    // 1-based index of the machine within the plant, all machines of the kind if zero
+		Products []string `json:"products"` // IDs of the products the machine makes in turn, a batch each
+	}
+
+	// ProductionPlan holds the products every machine of a plant makes in turn.
+	ProductionPlan map[string][]*ProductConfig
+)
+
+// Products gets the products the n-th machine of a kind makes in turn, none if the plan has no products for it.
+func (p ProductionPlan) Products(kind string, index int) []*ProductConfig {
+	return p[machineKey(kind, index)]
+}
+
+// getBatchProduct gets the product made in the n-th batch of the given local day, taking the products in turn over
+// consecutive batches.
+func getBatchProduct(products []*ProductConfig, day time.Time, batchNumber int, batchDurationHours int) *ProductConfig {
+	if len(products) == 0 {
+		return nil
+	}
+	days := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+	batchesPerDay := (24 + batchDurationHours - 1) / batchDurationHours
+	batch := int(days)*batchesPerDay + batchNumber - 1
+	return products[batch%len(products)]
+}
+
+// getDefects draws the number of defective parts among the parts made at the given defect rate.
+func getDefects(parts int, rate float64, rand *rand.Rand) int {
+	if parts <= 0 || rate <= 0 {
+		return 0
+	}
+	mean := float64(parts) * rate
+	defects := int(math.Round(mean + rand.NormFloat64()*math.Sqrt(mean*(1-rate))))
+	if defects < 0 {
+		return 0
+	}
+	if defects > parts {
+		return parts
+	}
+	return defects
+}
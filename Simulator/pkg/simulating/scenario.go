@@ -0,0 +1,142 @@
+package simulating
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	ScenarioSet    = "set"    // reports a fixed value for a telemetry field, e.g. an overheating machine
+	ScenarioScale  = "scale"  // multiplies a numeric telemetry field, e.g. an energy spike
+	ScenarioOutage = "outage" // the devices lose power and send nothing
+	ScenarioFault  = "fault"  // the machines break down with the given reason
+
+	ReasonPowerOutage = "POWER_OUTAGE"
+)
+
+type (
+	// ScenarioEvent is a fault or event injected into devices for a while.
+	ScenarioEvent struct {
+		At       time.Duration `json:"at"`       // start of the event after the simulator started, e.g. +2h
+		Duration time.Duration `json:"duration"` // how long the event lasts
+		Target   string        `json:"target"`   // device ID, plant name, or * for all devices
+		Type     string        `json:"type"`     // set, scale, outage or fault
+		Field    string        `json:"field"`    // telemetry field of a set or scale event
+		Value    interface{}   `json:"value"`    // value of a set event
+		Factor   float64       `json:"factor"`   // factor of a scale event
+		Reason   string        `json:"reason"`   // downtime reason of a fault event
+	}
+
+	// Scenario is a timeline of events played alongside the normal telemetry of the devices.
+	Scenario struct {
+		Events  []ScenarioEvent `json:"events"`
+		started time.Time
+	}
+)
+
+// LoadScenario reads a scenario file in JSON or YAML, with its event times relative to the given start.
+func LoadScenario(path string, start time.Time) (*Scenario, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	scenario := &Scenario{started: start}
+	if err := v.Unmarshal(scenario); err != nil {
+		return nil, err
+	}
+
+	for i := range scenario.Events {
+		event := &scenario.Events[i]
+		event.Type = strings.ToLower(event.Type)
+		if event.Target == "" || event.Duration <= 0 {
+			return nil, fmt.Errorf("event %d of scenario %s needs a target and a duration", i+1, path)
+		}
+		switch event.Type {
+		case ScenarioSet, ScenarioScale:
+			if event.Field == "" {
+				return nil, fmt.Errorf("%s event %d of scenario %s needs a field", event.Type, i+1, path)
+			}
+		case ScenarioOutage, ScenarioFault:
+		default:
+			return nil, fmt.Errorf("event %d of scenario %s has unknown type %s", i+1, path, event.Type)
+		}
+	}
+	sort.SliceStable(scenario.Events, func(i, j int) bool {
+		return scenario.Events[i].At < scenario.Events[j].At
+	})
+	return scenario, nil
+}
+
+// Play logs the start and end of every event on the timeline until the context is done. The devices apply the
+// events themselves.
+func (s *Scenario) Play(ctx context.Context) {
+	type mark struct {
+		at    time.Time
+		event *ScenarioEvent
+		start bool
+	}
+	var marks []mark
+	for i := range s.Events {
+		event := &s.Events[i]
+		marks = append(marks, mark{s.started.Add(event.At), event, true}, mark{s.started.Add(event.At + event.Duration), event, false})
+	}
+	sort.SliceStable(marks, func(i, j int) bool {
+		return marks[i].at.Before(marks[j].at)
+	})
+
+	for _, m := range marks {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(m.at)):
+		}
+		msg := "scenario event ended"
+		if m.start {
+			msg = "scenario event started"
+		}
+		log.Info().Str("type", m.event.Type).Str("target", m.event.Target).Str("field", m.event.Field).Msg(msg)
+	}
+}
+
+// activeEvents gets the events that apply to a device of a plant at the given time.
+func (s *Scenario) activeEvents(deviceID string, plantName string, now time.Time) []*ScenarioEvent {
+	if s == nil {
+		return nil
+	}
+	var active []*ScenarioEvent
+	for i := range s.Events {
+		event := &s.Events[i]
+		start := s.started.Add(event.At)
+		if now.Before(start) || !now.Before(start.Add(event.Duration)) {
+			continue
+		}
+		if event.Target == "*" || strings.EqualFold(event.Target, deviceID) || strings.EqualFold(event.Target, plantName) {
+			active = append(active, event)
+		}
+	}
+	return active
+}
+
+// apply changes the telemetry values of a device as the set and scale events describe.
+func (e *ScenarioEvent) apply(telemetry models.Telemetry) {
+	value, ok := telemetry[e.Field]
+	if !ok {
+		return
+	}
+	switch e.Type {
+	case ScenarioSet:
+		telemetry[e.Field] = e.Value
+	case ScenarioScale:
+		if f, ok := getFloatValue(value); ok {
+			telemetry[e.Field] = f * e.Factor
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package simulating
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestamp layouts accepted in the first column of an hourly series file
+var hourlyTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// layout of the key of an hour of the year, the month, day and hour
+const hourOfYearLayout = "01-02T15"
+
+// hourlySeries holds hourly values read from a CSV file, like carbon intensities or day-ahead prices.
+type hourlySeries struct {
+	hours    map[time.Time]float64 // value of every hour the file has a timestamp for
+	yearly   map[string]float64    // average value of every hour of the year in the file, by month, day and hour
+	daily    [24]float64           // value of every local hour of the day in the file
+	hasDaily [24]bool
+	utc      [24]float64 // average value of every UTC hour of the day the file has timestamps for
+	hasUTC   [24]bool
+}
+
+// readHourlySeries reads a CSV file of hourly values. Every row holds a timestamp in UTC, or an hour of the day from
+// 0 to 23 in the local time of the plant, followed by the value. Rows that do not parse, like a header, are skipped.
+func readHourlySeries(path string) (*hourlySeries, error) {
+	series, err := readHourlyColumns(path, 1)
+	if err != nil {
+		return nil, err
+	}
+	return series[0], nil
+}
+
+// readHourlyColumns reads a CSV file with several values per hour, like a weather file, into a series for each of the
+// given number of columns after the timestamp. A row is skipped for a column it has no value for.
+func readHourlyColumns(path string, columns int) ([]*hourlySeries, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]*hourlySeries, columns)
+	for column := range series {
+		series[column] = newHourlySeries(rows, column+1)
+	}
+	return series, nil
+}
+
+func newHourlySeries(rows [][]string, column int) *hourlySeries {
+	s := &hourlySeries{hours: map[time.Time]float64{}, yearly: map[string]float64{}}
+	var sums, utcSums [24]float64
+	var counts, utcCounts [24]int
+	yearlySums := map[string]float64{}
+	yearlyCounts := map[string]int{}
+	for _, row := range rows {
+		if len(row) <= column {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(row[column]), 64)
+		if err != nil {
+			continue
+		}
+		hour, h, ok := parseHour(strings.TrimSpace(row[0]))
+		if !ok {
+			continue
+		}
+		if !hour.IsZero() {
+			s.hours[hour] = value
+			yearlySums[hour.Format(hourOfYearLayout)] += value
+			yearlyCounts[hour.Format(hourOfYearLayout)]++
+			utcSums[h] += value
+			utcCounts[h]++
+		} else {
+			sums[h] += value
+			counts[h]++
+		}
+	}
+	for h := range sums {
+		if counts[h] > 0 {
+			s.daily[h] = sums[h] / float64(counts[h])
+			s.hasDaily[h] = true
+		}
+		if utcCounts[h] > 0 {
+			s.utc[h] = utcSums[h] / float64(utcCounts[h])
+			s.hasUTC[h] = true
+		}
+	}
+	for key, sum := range yearlySums {
+		s.yearly[key] = sum / float64(yearlyCounts[key])
+	}
+	return s
+}
+
+// get gets the value of the hour of the given local time, else the value of the same local hour of the day, else the
+// average of the same UTC hour of the day in the file. Timestamps are matched in UTC, hours of the day in local time.
+func (s *hourlySeries) get(at time.Time) (float64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	utc := at.UTC()
+	if value, ok := s.hours[utc.Truncate(time.Hour)]; ok {
+		return value, true
+	}
+	if s.hasDaily[at.Hour()] {
+		return s.daily[at.Hour()], true
+	}
+	return s.utc[utc.Hour()], s.hasUTC[utc.Hour()]
+}
+
+// getSeasonal gets the value of the hour of the given time, else the average of the same hour of the year in the
+// file, else that of the same hour of the day, so that a file of another year still follows the seasons.
+func (s *hourlySeries) getSeasonal(at time.Time) (float64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	utc := at.UTC()
+	if _, ok := s.hours[utc.Truncate(time.Hour)]; !ok {
+		if value, ok := s.yearly[utc.Format(hourOfYearLayout)]; ok {
+			return value, true
+		}
+	}
+	return s.get(at)
+}
+
+// parseHour parses the first column of a row into its hour, which is zero for an hour of the day, and its hour of the
+// day, in UTC for a timestamp.
+func parseHour(value string) (time.Time, int, bool) {
+	if h, err := strconv.Atoi(value); err == nil {
+		return time.Time{}, h, h >= 0 && h < 24
+	}
+	for _, layout := range hourlyTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC().Truncate(time.Hour)
+			return t, t.Hour(), true
+		}
+	}
+	return time.Time{}, 0, false
+}
@@ -0,0 +1,39 @@
+package simulating
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHourlySeriesInLocalTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "carbon.csv")
+	rows := "hour,intensity\n9,100\n11,300\n2022-05-24T13:00:00Z,500\n"
+	if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fallback := 200.0
+	carbon, err := NewCarbonProfile(&CarbonConfig{File: path, Intensity: &fallback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plant := time.FixedZone("UTC+2", 2*60*60)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"hour of the day in local time", time.Date(2022, 5, 25, 9, 0, 0, 0, plant), 100},
+		{"hour of the day not shifted to UTC", time.Date(2022, 5, 25, 11, 30, 0, 0, plant), 300},
+		{"timestamp in UTC", time.Date(2022, 5, 24, 15, 0, 0, 0, plant), 500},
+		{"timestamp average by UTC hour", time.Date(2022, 5, 25, 15, 0, 0, 0, plant), 500},
+		{"fallback", time.Date(2022, 5, 25, 14, 0, 0, 0, plant), 200},
+	}
+	for _, test := range tests {
+		if intensity := carbon.Intensity(test.at); intensity != test.want {
+			t.Errorf("%s: Intensity(%v) = %v, want %v", test.name, test.at, intensity, test.want)
+		}
+	}
+}
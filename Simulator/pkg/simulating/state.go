@@ -0,0 +1,454 @@
+package simulating
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	StateRunning           = "Running"           // the machine is producing
+	StateIdle              = "Idle"              // the machine is ready but waiting for work
+	StateChangeover        = "Changeover"        // the machine is being set up for the next product
+	StatePlannedDowntime   = "PlannedDowntime"   // the machine is stopped on purpose, e.g. for scheduled maintenance
+	StateUnplannedDowntime = "UnplannedDowntime" // the machine has failed and is being repaired
+	StateOff               = "Off"               // the machine is switched off and only draws standby power
+
+	ReasonMachineFailure     = "MACHINE_FAILURE"
+	ReasonChangeover         = "CHANGEOVER"
+	ReasonNoOrder            = "NO_ORDER"
+	ReasonPlannedMaintenance = "PLANNED_MAINTENANCE"
+	ReasonSwitchedOff        = "SWITCHED_OFF"
+	ReasonLowOil             = "LOW_OIL"
+	ReasonScheduledRefill    = "SCHEDULED_OIL_REFILL"
+
+	minEventStep = time.Second // shortest time drawn from a distribution, so that a state machine always moves forward
+)
+
+type (
+	// Distribution is a random distribution of durations.
+	Distribution struct {
+		Type   string        `json:"type"`   // exponential (default), normal, uniform or fixed
+		Mean   time.Duration `json:"mean"`   // mean duration; zero disables the event
+		StdDev time.Duration `json:"stdDev"` // standard deviation of a normal distribution
+		Min    time.Duration `json:"min"`    // lower bound of a uniform distribution
+		Max    time.Duration `json:"max"`    // upper bound of a uniform distribution
+	}
+
+	// EventConfig configures a recurring event that takes the machine out of production.
+	EventConfig struct {
+		Every    Distribution `json:"every"`    // time between two events
+		Duration Distribution `json:"duration"` // time the machine spends in the event
+	}
+
+	// DowntimeWindow is a planned downtime that recurs every day at the same local time.
+	DowntimeWindow struct {
+		Start    string        `json:"start"`    // local start time, HH:MM
+		Duration time.Duration `json:"duration"` // length of the window
+		Days     []string      `json:"days"`     // weekdays the window applies to, every day if empty
+		Reason   string        `json:"reason"`   // downtime reason code
+	}
+
+	// StateConfig configures the state machine of a machine kind.
+	StateConfig struct {
+		MTBF            Distribution     `json:"mtbf"`            // running time between failures
+		MTTR            Distribution     `json:"mttr"`            // time to repair a failure
+		FailureReasons  []string         `json:"failureReasons"`  // reason codes picked at random for failures
+		Changeover      EventConfig      `json:"changeover"`      // product changeovers
+		Idle            EventConfig      `json:"idle"`            // waiting for work
+		PlannedDowntime []DowntimeWindow `json:"plannedDowntime"` // daily planned downtime windows
+	}
+
+	// StateMachine tracks the operating state of a single machine.
+	StateMachine struct {
+		cfg            *StateConfig
+		calendar       *ShiftCalendar // shift calendar outside of which the machine does not produce, if any
+		location       *time.Location // time zone of the plant, which the planned downtime windows follow
+		rand           *rand.Rand     // random source of the device
+		state          string         // current state
+		reason         string         // downtime reason code of the current state
+		since          time.Time      // time the current state was entered
+		until          time.Time      // time a non running state ends
+		last           time.Time      // time of the previous advance
+		held           bool           // the machine holds a downtime state until it releases it
+		heldState      string         // state the machine holds
+		heldReason     string         // downtime reason code of the state the machine holds
+		scenarioHeld   bool           // a scenario event holds a downtime state on top of the hold of the machine
+		constraint     string         // Starved or Blocked while the production line limits the running machine
+		constrained    time.Time      // time the constraint started
+		nextFailure    time.Time
+		nextChangeover time.Time
+		nextIdle       time.Time
+	}
+)
+
+// defaultStateConfig gets the state configuration used for machine kinds without one.
+func defaultStateConfig() *StateConfig {
+	return &StateConfig{
+		MTBF:           Distribution{Type: "exponential", Mean: 8 * time.Hour},
+		MTTR:           Distribution{Type: "exponential", Mean: 20 * time.Minute},
+		FailureReasons: []string{"JAM", "MOTOR_FAULT", "SENSOR_FAULT"},
+		Changeover: EventConfig{
+			Every:    Distribution{Type: "fixed", Mean: 4 * time.Hour},
+			Duration: Distribution{Type: "normal", Mean: 15 * time.Minute, StdDev: 3 * time.Minute},
+		},
+		Idle: EventConfig{
+			Every:    Distribution{Type: "exponential", Mean: 3 * time.Hour},
+			Duration: Distribution{Type: "exponential", Mean: 5 * time.Minute},
+		},
+	}
+}
+
+// validate checks the distributions of the events, so that an event that takes no time fails at startup instead of
+// repeating forever.
+func (c *StateConfig) validate() error {
+	events := []struct {
+		name     string
+		every    *Distribution
+		duration *Distribution
+	}{
+		{"failure", &c.MTBF, &c.MTTR},
+		{"changeover", &c.Changeover.Every, &c.Changeover.Duration},
+		{"idle", &c.Idle.Every, &c.Idle.Duration},
+	}
+	for _, event := range events {
+		if !event.every.enabled() {
+			continue
+		}
+		if err := event.every.validate(); err != nil {
+			return fmt.Errorf("time between %s events %w", event.name, err)
+		}
+		if err := event.duration.validate(); err != nil {
+			return fmt.Errorf("duration of %s events %w", event.name, err)
+		}
+	}
+	return nil
+}
+
+// NewStateMachine creates a state machine that starts running at the given time, and stops between the shifts of the
+// calendar if there is one. Planned downtime windows and shifts are in the given time zone.
+func NewStateMachine(cfg *StateConfig, calendar *ShiftCalendar, location *time.Location, now time.Time, rand *rand.Rand) *StateMachine {
+	if cfg == nil {
+		cfg = defaultStateConfig()
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	s := &StateMachine{
+		cfg:      cfg,
+		calendar: calendar,
+		location: location,
+		rand:     rand,
+		state:    StateRunning,
+		since:    now,
+		last:     now,
+	}
+	s.nextFailure = s.schedule(now, &cfg.MTBF)
+	s.nextChangeover = s.schedule(now, &cfg.Changeover.Every)
+	s.nextIdle = s.schedule(now, &cfg.Idle.Every)
+	return s
+}
+
+// Advance moves the state machine forward to the given time and returns how long the machine was running, and how long
+// it was in planned downtime, since the previous advance.
+func (s *StateMachine) Advance(now time.Time) (time.Duration, time.Duration) {
+	running, planned := time.Duration(0), time.Duration(0)
+	if s.held || s.scenarioHeld {
+		if s.state == StatePlannedDowntime {
+			planned = now.Sub(s.last)
+		}
+		s.last = now
+		return running, planned
+	}
+	for s.last.Before(now) {
+		if s.state != StateRunning {
+			if s.state == StatePlannedDowntime {
+				end := s.until
+				if end.After(now) {
+					end = now
+				}
+				planned += maxDuration(0, end.Sub(s.last))
+			}
+			if s.until.After(now) {
+				s.last = now
+				break
+			}
+			s.last = s.until
+			s.enter(StateRunning, "", s.until)
+			// failures are counted in running time, so the clock restarts after a stop
+			s.nextFailure = s.schedule(s.since, &s.cfg.MTBF)
+			continue
+		}
+
+		at, ok := s.startNextEvent(now)
+		if !ok {
+			running += now.Sub(s.last)
+			s.last = now
+			break
+		}
+		running += at.Sub(s.last)
+		s.last = at
+	}
+	return running, planned
+}
+
+// Skip moves the state machine forward to the given time without simulating it, e.g. while the machine is switched off.
+func (s *StateMachine) Skip(now time.Time) {
+	delta := now.Sub(s.last)
+	for _, t := range []*time.Time{&s.since, &s.until, &s.nextFailure, &s.nextChangeover, &s.nextIdle} {
+		if !t.IsZero() {
+			*t = t.Add(delta)
+		}
+	}
+	s.last = now
+}
+
+// Hold keeps the machine in the given state from the given time until it is released, e.g. while it waits for maintenance.
+// A scenario event that holds the machine goes first, the hold of the machine applies once the event ends.
+func (s *StateMachine) Hold(state string, reason string, at time.Time) {
+	if s.held && s.heldState == state && s.heldReason == reason {
+		return
+	}
+	s.held, s.heldState, s.heldReason = true, state, reason
+	if !s.scenarioHeld {
+		s.enter(state, reason, at)
+	}
+}
+
+// Release lets a machine that holds a state run again from the given time, unless a scenario event holds it.
+func (s *StateMachine) Release(at time.Time) {
+	if !s.held {
+		return
+	}
+	s.held = false
+	if !s.scenarioHeld {
+		s.run(at)
+	}
+}
+
+// HoldScenario keeps the machine in the given state from the given time while a scenario event lasts, e.g. an outage.
+func (s *StateMachine) HoldScenario(state string, reason string, at time.Time) {
+	if s.scenarioHeld && s.state == state && s.reason == reason {
+		return
+	}
+	s.scenarioHeld = true
+	s.enter(state, reason, at)
+}
+
+// ReleaseScenario ends the hold of a scenario event at the given time, after which the machine returns to the state it
+// holds itself, if any, or runs again.
+func (s *StateMachine) ReleaseScenario(at time.Time) {
+	if !s.scenarioHeld {
+		return
+	}
+	s.scenarioHeld = false
+	if s.held {
+		s.enter(s.heldState, s.heldReason, at)
+		return
+	}
+	s.run(at)
+}
+
+// Constrain marks a running machine as starved or blocked by its production line, or clears the mark if the state is empty.
+func (s *StateMachine) Constrain(state string, at time.Time) {
+	if s.constraint != state {
+		s.constraint = state
+		s.constrained = at
+	}
+}
+
+// Current gets the current state.
+func (s *StateMachine) Current() string {
+	if s.state == StateRunning && s.constraint != "" {
+		return s.constraint
+	}
+	return s.state
+}
+
+// Reason gets the downtime reason code of the current state, empty while running.
+func (s *StateMachine) Reason() string {
+	if s.state == StateRunning {
+		switch s.constraint {
+		case StateStarved:
+			return ReasonStarved
+		case StateBlocked:
+			return ReasonBlocked
+		}
+	}
+	return s.reason
+}
+
+// Duration gets how long the machine has been in the current state.
+func (s *StateMachine) Duration(now time.Time) time.Duration {
+	if s.state == StateRunning && s.constraint != "" {
+		return now.Sub(maxTime(s.since, s.constrained))
+	}
+	return now.Sub(s.since)
+}
+
+// run lets the machine run again from the given time after a hold.
+func (s *StateMachine) run(at time.Time) {
+	s.enter(StateRunning, "", at)
+	s.nextFailure = s.schedule(at, &s.cfg.MTBF)
+}
+
+func (s *StateMachine) enter(state string, reason string, at time.Time) {
+	s.state = state
+	s.reason = reason
+	s.since = at
+}
+
+// startNextEvent takes a running machine out of production if an event happens before the given time, and returns when it happened.
+func (s *StateMachine) startNextEvent(now time.Time) (time.Time, bool) {
+	at, event := s.nextFailure, &s.nextFailure
+	for _, next := range []*time.Time{&s.nextChangeover, &s.nextIdle} {
+		if !next.IsZero() && (at.IsZero() || next.Before(at)) {
+			at, event = *next, next
+		}
+	}
+	windowStart, window := s.nextDowntimeWindow(s.last)
+	if window != nil && (at.IsZero() || windowStart.Before(at)) {
+		at, event = windowStart, nil
+	}
+	if at.IsZero() || at.After(now) {
+		return time.Time{}, false
+	}
+
+	// events that were due while the machine was stopped happen as soon as it runs again
+	at = maxTime(at, s.last)
+	switch event {
+	case nil:
+		reason := window.Reason
+		if reason == "" {
+			reason = ReasonPlannedMaintenance
+		}
+		s.enter(StatePlannedDowntime, reason, at)
+		s.until = windowStart.Add(window.Duration)
+	case &s.nextFailure:
+		s.enter(StateUnplannedDowntime, s.getFailureReason(), at)
+		s.until = at.Add(s.cfg.MTTR.sample(s.rand))
+		s.nextFailure = time.Time{}
+	case &s.nextChangeover:
+		s.enter(StateChangeover, ReasonChangeover, at)
+		s.until = at.Add(s.cfg.Changeover.Duration.sample(s.rand))
+		s.nextChangeover = s.schedule(at, &s.cfg.Changeover.Every)
+	case &s.nextIdle:
+		s.enter(StateIdle, ReasonNoOrder, at)
+		s.until = at.Add(s.cfg.Idle.Duration.sample(s.rand))
+		s.nextIdle = s.schedule(at, &s.cfg.Idle.Every)
+	}
+	return at, true
+}
+
+// nextDowntimeWindow gets the start of the first planned downtime window or stop of the shift calendar that is active
+// at or starts after the given time.
+func (s *StateMachine) nextDowntimeWindow(after time.Time) (time.Time, *DowntimeWindow) {
+	after = after.In(s.location)
+	var start time.Time
+	var next *DowntimeWindow
+	for i := range s.cfg.PlannedDowntime {
+		window := &s.cfg.PlannedDowntime[i]
+		var hour, minute int
+		if _, err := fmt.Sscanf(window.Start, "%d:%d", &hour, &minute); err != nil || window.Duration <= 0 {
+			continue
+		}
+		// look back one day for a window that is still active, and ahead a week for weekday windows
+		for day := -1; day <= 7; day++ {
+			d := after.AddDate(0, 0, day)
+			candidate := time.Date(d.Year(), d.Month(), d.Day(), hour, minute, 0, 0, after.Location())
+			if !candidate.Add(window.Duration).After(after) || !window.appliesTo(candidate.Weekday()) {
+				continue
+			}
+			if next == nil || candidate.Before(start) {
+				start, next = candidate, window
+			}
+			break
+		}
+	}
+	if stop, ok := s.calendar.nextStop(after); ok && (next == nil || stop.start.Before(start)) {
+		start, next = stop.start, &DowntimeWindow{Duration: stop.end.Sub(stop.start), Reason: stop.reason}
+	}
+	return start, next
+}
+
+func (w *DowntimeWindow) appliesTo(weekday time.Weekday) bool {
+	return appliesToDay(w.Days, weekday)
+}
+
+// appliesToDay tells whether a list of weekday names or abbreviations includes the weekday, an empty list includes every day.
+func appliesToDay(days []string, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		if strings.EqualFold(day, weekday.String()) || strings.EqualFold(day, weekday.String()[:3]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *StateMachine) getFailureReason() string {
+	if len(s.cfg.FailureReasons) == 0 {
+		return ReasonMachineFailure
+	}
+	return s.cfg.FailureReasons[s.rand.Intn(len(s.cfg.FailureReasons))]
+}
+
+// schedule gets the time of the next event drawn from the distribution, or zero if the event is disabled.
+func (s *StateMachine) schedule(from time.Time, every *Distribution) time.Time {
+	if !every.enabled() {
+		return time.Time{}
+	}
+	return from.Add(every.sample(s.rand))
+}
+
+// enabled tells whether the distribution schedules events, which a zero mean and maximum disable.
+func (d *Distribution) enabled() bool {
+	return d.Mean > 0 || d.Max > 0
+}
+
+// validate checks that the distribution draws positive durations.
+func (d *Distribution) validate() error {
+	if strings.EqualFold(d.Type, "uniform") {
+		if d.Min < 0 || d.Max <= 0 || d.Max < d.Min {
+			return fmt.Errorf("needs 0 <= min <= max and a positive max, got %s to %s", d.Min, d.Max)
+		}
+		return nil
+	}
+	if d.Mean <= 0 {
+		return fmt.Errorf("needs a positive mean, got %s", d.Mean)
+	}
+	return nil
+}
+
+// sample draws a duration from the distribution, at least the minimum step.
+func (d *Distribution) sample(rand *rand.Rand) time.Duration {
+	var value float64
+	switch strings.ToLower(d.Type) {
+	case "fixed":
+		value = float64(d.Mean)
+	case "normal":
+		value = rand.NormFloat64()*float64(d.StdDev) + float64(d.Mean)
+	case "uniform":
+		value = float64(d.Min) + rand.Float64()*float64(d.Max-d.Min)
+	default:
+		value = rand.ExpFloat64() * float64(d.Mean)
+	}
+	return maxDuration(minEventStep, time.Duration(value))
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func maxDuration(a time.Duration, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,83 @@
+package simulating
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestStateConfigRejectsZeroDistributions(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  StateConfig
+	}{
+		{"uniform without max", StateConfig{Idle: EventConfig{
+			Every:    Distribution{Type: "uniform", Mean: time.Hour},
+			Duration: Distribution{Mean: time.Minute},
+		}}},
+		{"fixed without mean", StateConfig{Changeover: EventConfig{
+			Every:    Distribution{Type: "fixed", Max: time.Hour},
+			Duration: Distribution{Mean: time.Minute},
+		}}},
+		{"zero duration", StateConfig{MTBF: Distribution{Mean: time.Hour}}},
+	}
+	for _, test := range tests {
+		if err := test.cfg.validate(); err == nil {
+			t.Errorf("%s: validate() = nil, want an error", test.name)
+		}
+	}
+	if err := defaultStateConfig().validate(); err != nil {
+		t.Errorf("default: validate() = %v, want nil", err)
+	}
+}
+
+func TestAdvanceWithZeroLengthEvents(t *testing.T) {
+	cfg := &StateConfig{Changeover: EventConfig{
+		Every:    Distribution{Type: "fixed", Mean: time.Nanosecond},
+		Duration: Distribution{Type: "fixed"},
+	}}
+	start := time.Date(2022, 5, 24, 9, 0, 0, 0, time.UTC)
+	machine := NewStateMachine(cfg, nil, time.UTC, start, rand.New(rand.NewSource(1)))
+
+	done := make(chan time.Duration)
+	go func() {
+		running, _ := machine.Advance(start.Add(time.Minute))
+		done <- running
+	}()
+	select {
+	case running := <-done:
+		// the changeovers of a second follow each other after the first second
+		if running != time.Second {
+			t.Errorf("running = %v, want 1s", running)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Advance did not return")
+	}
+}
+
+func TestScenarioFaultKeepsToolBreakage(t *testing.T) {
+	start := time.Date(2022, 5, 24, 9, 0, 0, 0, time.UTC)
+	machine := NewStateMachine(&StateConfig{}, nil, time.UTC, start, rand.New(rand.NewSource(1)))
+
+	// the tool breaks during a scenario fault, which ends before the tool is replaced
+	machine.HoldScenario(StateUnplannedDowntime, ReasonMachineFailure, start)
+	machine.Hold(StateUnplannedDowntime, ReasonToolBreakage, start.Add(time.Minute))
+	if reason := machine.Reason(); reason != ReasonMachineFailure {
+		t.Errorf("reason during the fault = %s, want %s", reason, ReasonMachineFailure)
+	}
+	machine.ReleaseScenario(start.Add(2 * time.Minute))
+	if state, reason := machine.Current(), machine.Reason(); state != StateUnplannedDowntime || reason != ReasonToolBreakage {
+		t.Errorf("after the fault = %s %s, want %s %s", state, reason, StateUnplannedDowntime, ReasonToolBreakage)
+	}
+
+	// a repair during a scenario fault does not end the fault
+	machine.HoldScenario(StateUnplannedDowntime, ReasonMachineFailure, start.Add(3*time.Minute))
+	machine.Release(start.Add(4 * time.Minute))
+	if reason := machine.Reason(); reason != ReasonMachineFailure {
+		t.Errorf("reason after the repair = %s, want %s", reason, ReasonMachineFailure)
+	}
+	machine.ReleaseScenario(start.Add(5 * time.Minute))
+	if state := machine.Current(); state != StateRunning {
+		t.Errorf("after the fault and the repair = %s, want %s", state, StateRunning)
+	}
+}
@@ -0,0 +1,121 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const defaultEnergyPrice = 0.25 // euro per kWh of a plant without a tariff
+
+type (
+	// TariffConfig configures the electricity tariff of a plant. Prices are in euro per kWh.
+	TariffConfig struct {
+		Price   *float64     `json:"price"`   // price outside the bands, defaults to 0.25
+		Weekend *float64     `json:"weekend"` // price all day on Saturday and Sunday, the weekday prices if not set
+		Bands   []TariffBand `json:"bands"`   // time-of-use bands, the first band that applies sets the price
+		File    string       `json:"file"`    // CSV file of day-ahead prices, by timestamp or hour of the day, overrides the other prices
+	}
+
+	// TariffBand is a time-of-use band that recurs every day.
+	TariffBand struct {
+		Start string   `json:"start"` // local start time, HH:MM
+		End   string   `json:"end"`   // local end time, HH:MM, before the start for a band past midnight
+		Days  []string `json:"days"`  // weekdays the band applies to, every day if empty
+		Price float64  `json:"price"` // price within the band
+	}
+
+	// Tariff gets the electricity price of a plant over time.
+	Tariff struct {
+		cfg    *TariffConfig
+		price  float64 // price outside the bands
+		bands  []tariffBand
+		series *hourlySeries // day-ahead prices read from the file, if any
+	}
+
+	tariffBand struct {
+		*TariffBand
+		start int // minute of the day the band starts
+		end   int // minute of the day the band ends
+	}
+)
+
+// NewTariff creates the tariff of a plant, reading its day-ahead prices if it has a file.
+func NewTariff(cfg *TariffConfig) (*Tariff, error) {
+	if cfg == nil {
+		cfg = &TariffConfig{}
+	}
+	t := &Tariff{cfg: cfg, price: defaultEnergyPrice}
+	if cfg.Price != nil {
+		if *cfg.Price < 0 {
+			return nil, fmt.Errorf("tariff has a negative price %v", *cfg.Price)
+		}
+		t.price = *cfg.Price
+	}
+	if cfg.Weekend != nil && *cfg.Weekend < 0 {
+		return nil, fmt.Errorf("tariff has a negative weekend price %v", *cfg.Weekend)
+	}
+	for i := range cfg.Bands {
+		band := tariffBand{TariffBand: &cfg.Bands[i]}
+		if band.Price < 0 {
+			return nil, fmt.Errorf("tariff band %d has a negative price %v", i+1, band.Price)
+		}
+		var ok bool
+		if band.start, ok = parseMinuteOfDay(band.Start); !ok {
+			return nil, fmt.Errorf("tariff band %d has an invalid start %s", i+1, band.Start)
+		}
+		if band.end, ok = parseMinuteOfDay(band.End); !ok {
+			return nil, fmt.Errorf("tariff band %d has an invalid end %s", i+1, band.End)
+		}
+		t.bands = append(t.bands, band)
+	}
+	if cfg.File != "" {
+		series, err := readHourlySeries(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read day-ahead prices %s. %w", cfg.File, err)
+		}
+		t.series = series
+	}
+	return t, nil
+}
+
+// Price gets the price in euro per kWh at the given time, whose time zone the bands and the weekend follow.
+func (t *Tariff) Price(at time.Time) float64 {
+	if t == nil {
+		return defaultEnergyPrice
+	}
+	if price, ok := t.series.get(at); ok {
+		return price
+	}
+	if t.cfg.Weekend != nil && (at.Weekday() == time.Saturday || at.Weekday() == time.Sunday) {
+		return *t.cfg.Weekend
+	}
+	minute := at.Hour()*60 + at.Minute()
+	for _, band := range t.bands {
+		if band.contains(minute) && appliesToDay(band.Days, at.Weekday()) {
+			return band.Price
+		}
+	}
+	return t.price
+}
+
+func (b *tariffBand) contains(minute int) bool {
+	if b.start <= b.end {
+		return minute >= b.start && minute < b.end
+	}
+	// the band runs past midnight
+	return minute >= b.start || minute < b.end
+}
+
+func parseMinuteOfDay(value string) (int, bool) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil || hour < 0 || hour > 24 || minute < 0 || minute > 59 {
+		return 0, false
+	}
+	return hour*60 + minute, true
+}
+
+// getEnergyCost gets the cost in euro of the energy used at the given price.
+func getEnergyCost(kwh float64, price float64) float64 {
+	return math.Round(kwh*price*10000) / 10000
+}
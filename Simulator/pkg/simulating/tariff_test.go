@@ -0,0 +1,53 @@
+package simulating
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTariffKeepsZeroPrices(t *testing.T) {
+	zero := 0.0
+	tariff, err := NewTariff(&TariffConfig{
+		Price:   &zero,
+		Weekend: &zero,
+		Bands:   []TariffBand{{Start: "07:00", End: "23:00", Price: 0.31}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"outside the bands", time.Date(2022, 5, 24, 3, 0, 0, 0, time.UTC), 0},
+		{"in a band", time.Date(2022, 5, 24, 9, 0, 0, 0, time.UTC), 0.31},
+		{"weekend", time.Date(2022, 5, 28, 9, 0, 0, 0, time.UTC), 0},
+	}
+	for _, test := range tests {
+		if price := tariff.Price(test.at); price != test.want {
+			t.Errorf("%s: Price = %v, want %v", test.name, price, test.want)
+		}
+	}
+
+	tariff, err = NewTariff(&TariffConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := tariff.Price(time.Date(2022, 5, 28, 9, 0, 0, 0, time.UTC)); price != defaultEnergyPrice {
+		t.Errorf("default: Price = %v, want %v", price, defaultEnergyPrice)
+	}
+}
+
+func TestTariffRejectsNegativePrices(t *testing.T) {
+	negative := -0.1
+	for _, cfg := range []*TariffConfig{
+		{Price: &negative},
+		{Weekend: &negative},
+		{Bands: []TariffBand{{Start: "07:00", End: "23:00", Price: negative}}},
+	} {
+		if _, err := NewTariff(cfg); err == nil {
+			t.Errorf("NewTariff(%+v) = nil error, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/iot-for-all/iiot-oee/pkg/models"
+)
+
+const (
+	UtilityCompressedAir = "compressedAir" // compressed air for pneumatics and blow-off, in normal cubic metre
+	UtilityCoolingWater  = "coolingWater"  // cooling water of moulds, dies and coolant circuits, in cubic metre
+	UtilityNaturalGas    = "naturalGas"    // natural gas of burners and furnaces, in cubic metre
+
+	utilityNoise = 0.02 // relative noise on a utility meter reading
+)
+
+// utilityDefaults holds the unit and emissions factor in grams of CO2 per unit of the known utilities
+var utilityDefaults = map[string]struct {
+	unit      string
+	emissions float64
+}{
+	UtilityCompressedAir: {"Nm3", 35},  // about 0.12 kWh of compressor energy per normal cubic metre
+	UtilityCoolingWater:  {"m3", 350},  // supply and treatment of the water
+	UtilityNaturalGas:    {"m3", 1900}, // combustion of the gas
+}
+
+type (
+	// UtilityConfig configures a utility a machine consumes next to electricity.
+	UtilityConfig struct {
+		Name        string  `json:"name"`        // name of the utility, e.g. compressedAir, coolingWater or naturalGas
+		Unit        string  `json:"unit"`        // unit of the consumption, defaults for the known utilities
+		RunningRate float64 `json:"runningRate"` // consumption per hour while running, regardless of the output
+		PerPart     float64 `json:"perPart"`     // consumption per part or unit made
+		StandbyRate float64 `json:"standbyRate"` // consumption per hour while stopped or switched off
+		Emissions   float64 `json:"emissions"`   // grams of CO2 per unit consumed, defaults for the known utilities
+	}
+
+	// UtilityReading is the consumption of a utility over a step, as reported in the utilities telemetry.
+	UtilityReading struct {
+		Consumption float64 `json:"consumption"` // consumption since the previous message
+		Unit        string  `json:"unit"`        // unit of the consumption
+		Co2Grams    float64 `json:"co2grams"`    // grams of CO2 emitted for the consumption
+	}
+)
+
+// applyDefaults checks the utility and fills in the unit and emissions factor of a known utility.
+func (u *UtilityConfig) applyDefaults() error {
+	if u.Name == "" {
+		return fmt.Errorf("utility needs a name")
+	}
+	for name, defaults := range utilityDefaults {
+		if !strings.EqualFold(u.Name, name) {
+			continue
+		}
+		u.Name = name
+		if u.Unit == "" {
+			u.Unit = defaults.unit
+		}
+		if u.Emissions == 0 {
+			u.Emissions = defaults.emissions
+		}
+	}
+	if u.Unit == "" {
+		return fmt.Errorf("utility %s needs a unit", u.Name)
+	}
+	return nil
+}
+
+// getReading gets the consumption of the utility over a step, given the running time, stopped time, parts made and
+// ambient temperature, which the cooling water follows.
+func (u *UtilityConfig) getReading(runTime time.Duration, stopTime time.Duration, parts int, ambient float64, rand *rand.Rand) UtilityReading {
+	consumption := u.RunningRate*runTime.Hours() + u.PerPart*float64(parts) + u.StandbyRate*stopTime.Hours()
+	if u.Name == UtilityCoolingWater {
+		consumption *= getCoolingFactor(ambient)
+	}
+	consumption *= 1 + (rand.Float64()*2-1)*utilityNoise
+	consumption = math.Round(consumption*1000) / 1000
+	return UtilityReading{
+		Consumption: consumption,
+		Unit:        u.Unit,
+		Co2Grams:    getEmissions(consumption, u.Emissions),
+	}
+}
+
+// getPartsMade gets the parts a machine made over a step, from its telemetry or else the units it moved through its line.
+func getPartsMade(tick *Tick, telemetry models.Telemetry) int {
+	if parts, ok := getFloatValue(telemetry["totalPartsMade"]); ok {
+		return int(parts)
+	}
+	return tick.LineOutput
+}
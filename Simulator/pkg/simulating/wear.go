@@ -0,0 +1,80 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	MaintenanceScheduledToolChange = "SCHEDULED_TOOL_CHANGE" // tool changed when it reached its planned wear
+	MaintenanceCommandToolChange   = "COMMAND_TOOL_CHANGE"   // tool changed through the changeTool command
+	MaintenanceToolRepair          = "TOOL_REPAIR"           // broken tool replaced
+
+	ReasonToolChange   = "TOOL_CHANGE"   // the tool is being changed
+	ReasonToolBreakage = "TOOL_BREAKAGE" // the tool broke before it was changed
+)
+
+// WearConfig configures the wear of the tool of a machine kind, e.g. the dies of a bolt machine.
+type WearConfig struct {
+	ToolLife          int           `json:"toolLife"`          // parts a tool makes on average before it breaks
+	LifeVariation     float64       `json:"lifeVariation"`     // standard deviation of the life of a tool as a share of the tool life
+	ChangeAt          float64       `json:"changeAt"`          // share of the tool life after which the tool is changed, never if zero
+	ChangeDuration    time.Duration `json:"changeDuration"`    // time a tool change stops the machine
+	RepairDuration    time.Duration `json:"repairDuration"`    // time replacing a broken tool stops the machine
+	DefectIncrease    float64       `json:"defectIncrease"`    // extra defect rate of a tool at the end of its life
+	EnergyIncrease    float64       `json:"energyIncrease"`    // extra energy per part of a tool at the end of its life, e.g. 0.3 for 30%
+	Vibration         float64       `json:"vibration"`         // vibration of a running machine with a new tool in mm/s
+	VibrationIncrease float64       `json:"vibrationIncrease"` // extra vibration of a tool at the end of its life in mm/s
+	ExportRul         bool          `json:"exportRul"`         // report the remaining useful life of the tool as ground truth
+}
+
+// defaultWearConfig gets the wear configuration used for machine kinds without one.
+func defaultWearConfig() *WearConfig {
+	return &WearConfig{
+		ToolLife:          250000,
+		LifeVariation:     0.15,
+		ChangeAt:          0.9,
+		ChangeDuration:    20 * time.Minute,
+		RepairDuration:    90 * time.Minute,
+		DefectIncrease:    0.06,
+		EnergyIncrease:    0.3,
+		Vibration:         2,
+		VibrationIncrease: 6,
+	}
+}
+
+// validate checks the wear of the tool, so that a tool without a life fails at startup instead of breaking at once.
+func (w *WearConfig) validate() error {
+	if w.ToolLife <= 0 {
+		return fmt.Errorf("tool life needs to be positive, got %d", w.ToolLife)
+	}
+	if w.ChangeAt < 0 || w.ChangeAt > 1 {
+		return fmt.Errorf("tool change needs to be at a share of the tool life from 0 to 1, got %v", w.ChangeAt)
+	}
+	if w.ChangeDuration < 0 || w.RepairDuration < 0 {
+		return fmt.Errorf("tool change and repair durations cannot be negative, got %s and %s", w.ChangeDuration, w.RepairDuration)
+	}
+	return nil
+}
+
+// drawToolLife draws the number of parts a new tool makes before it breaks, which the machine does not know.
+func (w *WearConfig) drawToolLife(rand *rand.Rand) int {
+	life := float64(w.ToolLife) * (1 + rand.NormFloat64()*w.LifeVariation)
+	return int(math.Max(float64(w.ToolLife)/10, life))
+}
+
+// getWear gets how far a tool that made the given parts is into its actual life, from 0 for a new tool to 1 for a
+// tool that is about to break.
+func getWear(parts int, life int) float64 {
+	if life <= 0 {
+		return 0
+	}
+	return math.Min(1, float64(parts)/float64(life))
+}
+
+// getVibration gets the vibration of a running machine in mm/s, which grows faster as the tool nears its end of life.
+func (w *WearConfig) getVibration(wear float64, rand *rand.Rand) float64 {
+	return w.Vibration + w.VibrationIncrease*wear*wear + rand.Float64()*0.4 - 0.2
+}
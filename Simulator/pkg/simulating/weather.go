@@ -0,0 +1,85 @@
+package simulating
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	hallTemperature      = 20.0 // temperature the hall of machines that are not on a floor is heated to
+	referenceTemperature = 20.0 // ambient temperature the machine models are tuned for
+	defaultMean          = 10.0 // mean outdoor temperature of the daily cycle
+	defaultSwing         = 5.0  // difference between the mean and the warmest time of the daily cycle
+	defaultHumidity      = 70.0 // outdoor relative humidity in percent of the hours the weather file does not cover
+)
+
+// defaultWeather is the weather at plants without an outdoor configuration.
+var defaultWeather = &Weather{mean: defaultMean, swing: defaultSwing, humidity: defaultHumidity}
+
+// Weather gets the outdoor temperature and humidity at a plant over time, from its weather file where it covers the
+// hour, else from the daily cycle of its outdoor configuration.
+type Weather struct {
+	mean        float64
+	swing       float64
+	humidity    float64
+	temperature *hourlySeries // outdoor temperatures read from the file, if any
+	humidities  *hourlySeries // outdoor humidities read from the file, if any
+}
+
+// NewWeather reads the weather file of a plant, filling in the defaults of the values its outdoor configuration
+// leaves out. Every row of the file holds a timestamp in UTC, or a local hour of the day from 0 to 23, followed by the
+// temperature in °C and the relative humidity in percent, see readHourlySeries.
+func NewWeather(cfg *OutdoorConfig) (*Weather, error) {
+	if cfg == nil {
+		return defaultWeather, nil
+	}
+	w := &Weather{mean: defaultMean, swing: defaultSwing, humidity: defaultHumidity}
+	if cfg.Mean != nil {
+		w.mean = *cfg.Mean
+	}
+	if cfg.Swing != nil {
+		w.swing = *cfg.Swing
+	}
+	if cfg.Humidity != nil {
+		w.humidity = *cfg.Humidity
+	}
+	if cfg.File != "" {
+		series, err := readHourlyColumns(cfg.File, 2)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read weather file %s. %w", cfg.File, err)
+		}
+		w.temperature, w.humidities = series[0], series[1]
+	}
+	return w, nil
+}
+
+// Temperature gets the outdoor temperature in °C at the given local time.
+func (w *Weather) Temperature(at time.Time) float64 {
+	if w == nil {
+		w = defaultWeather
+	}
+	if temperature, ok := w.temperature.getSeasonal(at); ok {
+		return temperature
+	}
+	// the daily cycle is warmest at 15:00
+	hours := float64(at.Hour()) + float64(at.Minute())/60
+	return w.mean + w.swing*math.Cos((hours-15)/24*2*math.Pi)
+}
+
+// Humidity gets the outdoor relative humidity in percent at the given local time.
+func (w *Weather) Humidity(at time.Time) float64 {
+	if w == nil {
+		w = defaultWeather
+	}
+	if humidity, ok := w.humidities.getSeasonal(at); ok {
+		return humidity
+	}
+	return w.humidity
+}
+
+// getCoolingFactor gets how much cooling a machine needs at the given ambient temperature compared to the reference
+// temperature, 3% more for every degree warmer and at least half of it in the cold.
+func getCoolingFactor(ambient float64) float64 {
+	return math.Max(0.5, 1+0.03*(ambient-referenceTemperature))
+}
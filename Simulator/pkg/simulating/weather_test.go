@@ -0,0 +1,38 @@
+package simulating
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeatherKeepsZeroValues(t *testing.T) {
+	zero := 0.0
+	weather, err := NewWeather(&OutdoorConfig{Mean: &zero, Swing: &zero, Humidity: &zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2022, 1, 10, 15, 0, 0, 0, time.UTC)
+	if temperature := weather.Temperature(at); temperature != 0 {
+		t.Errorf("Temperature = %v, want 0", temperature)
+	}
+	if humidity := weather.Humidity(at); humidity != 0 {
+		t.Errorf("Humidity = %v, want 0", humidity)
+	}
+}
+
+func TestWeatherDefaults(t *testing.T) {
+	at := time.Date(2022, 1, 10, 15, 0, 0, 0, time.UTC)
+	var none *Weather
+	for _, weather := range []*Weather{none, defaultWeather} {
+		if temperature := weather.Temperature(at); temperature != defaultMean+defaultSwing {
+			t.Errorf("Temperature = %v, want %v", temperature, defaultMean+defaultSwing)
+		}
+	}
+	weather, err := NewWeather(&OutdoorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if temperature := weather.Temperature(at); temperature != defaultMean+defaultSwing {
+		t.Errorf("Temperature = %v, want %v", temperature, defaultMean+defaultSwing)
+	}
+}